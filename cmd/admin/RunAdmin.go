@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/initializers"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+)
+
+// Exit codes of the admin subcommands
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+const usage = `Usage: htmx_GO admin promote [flags] <email>
+
+Commands:
+  promote  give the superuser registered with <email> a role, e.g. to bootstrap the first administrator
+
+Flags:
+  -config string  path to the configuration file (default "config.yaml")
+  -role string    role to give (default "superadmin")
+`
+
+// RunAdmin runs an admin subcommand and returns the process exit code: 0 on success and 1 on any error.
+// It works on the database directly, so it is how the first administrator is created on a new installation.
+func RunAdmin(args []string) int {
+	if len(args) == 0 || args[0] != "promote" {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+
+	flags := flag.NewFlagSet("admin promote", flag.ContinueOnError)
+	configPath := flags.String("config", "config.yaml", "path to the configuration file")
+	role := flags.String("role", "superadmin", "role to give")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	email := flags.Arg(0)
+
+	if err := configs.InitializeServerConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize configuration: %v\n", err)
+		return exitError
+	}
+	policy, err := rbac.NewPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load RBAC policy: %v\n", err)
+		return exitError
+	}
+	if !policy.HasRole(*role) {
+		fmt.Fprintf(os.Stderr, "Unknown role %q, configured roles are in rbac.roles\n", *role)
+		return exitError
+	}
+
+	ctx := context.Background()
+	mongoCL, err := initializers.ConnectToMongoDB(ctx, configs.MongoDBUrl, 30*time.Second, 1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+		return exitError
+	}
+	defer mongoCL.Disconnect(ctx)
+	mongoDB := initializers.GetDatabase(mongoCL, "htmx_go")
+	repo := repositories.NewMongoSuperuserRepository(mongoDB)
+	auditService := services.NewAuditService(repositories.NewMongoAuditLogRepository(mongoDB))
+
+	superuser, err := repo.FindSuperuserByEmail(ctx, email)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "No superuser is registered with %s\n", email)
+		return exitError
+	}
+	if err := repo.UpdateSuperuserRole(ctx, superuser.ID, *role); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update role: %v\n", err)
+		return exitError
+	}
+
+	// No account performed the change, the entry is recorded without one
+	metadata := map[string]interface{}{"target_ids": []string{superuser.ID.String()}, "role": *role, "source": "cli"}
+	if err := auditService.Record(ctx, uuid.Nil, services.AuditRoleChanged, "", "", metadata); err != nil {
+		fmt.Fprintf(os.Stderr, "Role updated, but recording the audit log entry failed: %v\n", err)
+		return exitError
+	}
+
+	fmt.Printf("%s (%s) now has the %s role\n", superuser.Username, email, *role)
+	return exitOK
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/initializers"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+)
+
+// Exit codes of the audit subcommands
+const (
+	exitIntact = 0
+	exitBroken = 1
+	exitError  = 2
+)
+
+const usage = `Usage: htmx_GO audit <command> [flags]
+
+Commands:
+  verify      verify the audit log hash chain and report the first broken link
+  checkpoint  store a signed checkpoint of the current head of the chain
+
+Flags:
+  -config string  path to the configuration file (default "config.yaml")
+  -json           print the result as JSON
+`
+
+// RunAudit runs an audit subcommand and returns the process exit code:
+// 0 when the chain is intact, 1 when it is broken and 2 on any other error.
+func RunAudit(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+
+	command := args[0]
+	if command != "verify" && command != "checkpoint" {
+		fmt.Fprintf(os.Stderr, "unknown audit command %q\n\n%s", command, usage)
+		return exitError
+	}
+	flags := flag.NewFlagSet("audit "+command, flag.ContinueOnError)
+	configPath := flags.String("config", "config.yaml", "path to the configuration file")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+
+	if err := configs.InitializeServerConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize configuration: %v\n", err)
+		return exitError
+	}
+	ctx := context.Background()
+	mongoCL, err := initializers.ConnectToMongoDB(ctx, configs.MongoDBUrl, 30*time.Second, 1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+		return exitError
+	}
+	defer mongoCL.Disconnect(ctx)
+	auditService := services.NewAuditService(repositories.NewMongoAuditLogRepository(initializers.GetDatabase(mongoCL, "htmx_go")))
+
+	if command == "checkpoint" {
+		checkpoint, err := auditService.Checkpoint(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to store audit log checkpoint: %v\n", err)
+			return exitError
+		}
+		fmt.Printf("Stored checkpoint %d at entry %d\n", checkpoint.Number, checkpoint.Sequence)
+		return exitIntact
+	}
+
+	report, err := auditService.VerifyAuditChain(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to verify audit log: %v\n", err)
+		return exitError
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printReport(os.Stdout, report)
+	}
+	if !report.Intact {
+		return exitBroken
+	}
+	return exitIntact
+}
+
+// printReport writes a verification report for humans.
+func printReport(w io.Writer, report *services.AuditChainReport) {
+	fmt.Fprintf(w, "Entries verified: %d", report.Entries)
+	if report.Entries > 0 {
+		fmt.Fprintf(w, " (%d to %d)", report.FirstSequence, report.LastSequence)
+	}
+	fmt.Fprintf(w, ", checkpoints: %d\n", report.Checkpoints)
+	if report.Intact {
+		fmt.Fprintln(w, "Audit log intact")
+		return
+	}
+	fmt.Fprintf(w, "Audit log broken at entry %d: %s\n", report.Break.Sequence, report.Break.Reason)
+	if report.Break.EntryID != uuid.Nil {
+		fmt.Fprintf(w, "Entry ID: %s\n", report.Break.EntryID)
+	}
+}
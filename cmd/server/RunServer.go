@@ -3,7 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -13,34 +13,41 @@ import (
 	"github.com/lordofthemind/htmx_GO/internals/repositories"
 	"github.com/lordofthemind/htmx_GO/internals/routes"
 	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/pkgs/cookies"
+	"github.com/lordofthemind/htmx_GO/pkgs/email"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
 	"github.com/lordofthemind/htmx_GO/pkgs/middlewares"
+	"github.com/lordofthemind/htmx_GO/pkgs/passwords"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
 	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+	"github.com/lordofthemind/htmx_GO/pkgs/twofactor"
 )
 
 func RunServer() {
-	log.Println("Starting server...")
+	slog.Info("Starting server")
 
-	// Set up logging
-	logFile, err := initializers.SetUpLoggerFile("Server.log")
+	// Initialize server configuration, it holds the logging settings
+	err := configs.InitializeServerConfig("config.yaml")
 	if err != nil {
-		log.Fatalf("Failed to set up logger: %v", err)
+		logging.Fatal("Failed to initialize server configuration", "error", err)
 	}
-	defer logFile.Close()
 
-	// Initialize server configuration
-	err = configs.InitializeServerConfig("config.yaml")
+	// Set up logging
+	logFile, err := initializers.SetUpLoggerFile("Server.log")
 	if err != nil {
-		log.Fatalf("Failed to initialize server configuration: %v", err)
+		logging.Fatal("Failed to set up logger", "error", err)
 	}
+	defer logFile.Close()
 
 	// Set up the Gin router with optional CORS
 	router, err := initializers.SetUpServerWithOptionalCORS()
 	if err != nil {
-		log.Fatalf("Failed to set up Gin server: %v", err)
+		logging.Fatal("Failed to set up Gin server", "error", err)
 	}
 
-	// MongoDB connection and setup
-	ctx := context.Background()
+	// MongoDB connection and setup. Background work started below stops once the server has shut down.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	dsn := configs.MongoDBUrl
 	timeout := 30 * time.Second
 	maxRetries := 5
@@ -48,7 +55,7 @@ func RunServer() {
 	// Connect to MongoDB
 	mongoCL, err := initializers.ConnectToMongoDB(ctx, dsn, timeout, maxRetries)
 	if err != nil {
-		log.Fatalf("Error connecting to MongoDB: %v", err)
+		logging.Fatal("Error connecting to MongoDB", "error", err)
 	}
 	defer mongoCL.Disconnect(ctx)
 
@@ -65,25 +72,103 @@ func RunServer() {
 	// Set up service, handler, and middleware
 	repo := repositories.NewMongoSuperuserRepository(mongoDB)
 	// repo := repositories.NewInMemorySuperuserRepository()
-	service := services.NewSuperuserService(repo)
+	refreshRepo := repositories.NewMongoRefreshTokenRepository(mongoDB)
+	// refreshRepo := repositories.NewInMemoryRefreshTokenRepository()
+	sessionRepo := repositories.NewMongoSessionRepository(mongoDB)
+	// sessionRepo := repositories.NewInMemorySessionRepository(ctx, configs.SessionSweepInterval)
+	if err := repositories.CreateSessionIndexes(ctx, mongoDB); err != nil {
+		logging.Fatal("Failed to create session indexes", "error", err)
+	}
+	if err := repositories.CreateRefreshTokenIndexes(ctx, mongoDB); err != nil {
+		logging.Fatal("Failed to create refresh token indexes", "error", err)
+	}
+	apiKeyRepo := repositories.NewMongoAPIKeyRepository(mongoDB)
+	// apiKeyRepo := repositories.NewInMemoryAPIKeyRepository()
+	if err := repositories.CreateAPIKeyIndexes(ctx, mongoDB); err != nil {
+		logging.Fatal("Failed to create API key indexes", "error", err)
+	}
+	auditRepo := repositories.NewMongoAuditLogRepository(mongoDB)
+	// auditRepo := repositories.NewInMemoryAuditLogRepository(ctx, configs.AuditSweepInterval)
+	if err := repositories.CreateAuditLogIndexes(ctx, mongoDB); err != nil {
+		logging.Fatal("Failed to create audit log indexes", "error", err)
+	}
+
+	// Asymmetric tokens are signed with a rotating keyring
+	var keyring *tokens.Keyring
+	if configs.TokenSigning == "asymmetric" {
+		keyring, err = tokens.LoadKeyring(configs.KeyringPath, configs.KeyRotationInterval, configs.KeyRetireAfter)
+		if err != nil {
+			logging.Fatal("Failed to load token keyring", "error", err)
+		}
+		if err := keyring.RotateIfDue(); err != nil {
+			logging.Fatal("Failed to rotate token keyring", "error", err)
+		}
+		keyring.StartRotation(ctx, configs.KeyRotationCheck)
+	}
 
 	// Use the new NewTokenManager function
-	tokenManager, err := tokens.NewTokenManager()
+	tokenManager, err := tokens.NewTokenManager(keyring)
+	if err != nil {
+		logging.Fatal("Failed to initiate token", "error", err)
+	}
+
+	// Set up the TOTP manager used for two-factor authentication
+	totpManager, err := twofactor.NewTOTPManager()
 	if err != nil {
-		log.Fatalf("Failed to initiate token: %v", err)
+		logging.Fatal("Failed to initiate TOTP manager", "error", err)
 	}
-	handler := handlers.NewSuperuserHandler(service, tokenManager)
+
+	// Set up the mailer and the templates used for outgoing emails
+	mailer, err := email.NewMailer()
+	if err != nil {
+		logging.Fatal("Failed to initiate mailer", "error", err)
+	}
+	mailTemplates, err := email.NewTemplateRenderer(configs.EmailTemplatePath)
+	if err != nil {
+		logging.Fatal("Failed to load email templates", "error", err)
+	}
+
+	// Set up the role-based access control policy
+	policy, err := rbac.NewPolicy()
+	if err != nil {
+		logging.Fatal("Failed to load RBAC policy", "error", err)
+	}
+
+	// Set up the password policy, which loads the breached password list
+	passwordPolicy, err := passwords.NewPolicy()
+	if err != nil {
+		logging.Fatal("Failed to load password policy", "error", err)
+	}
+	sessionService := services.NewSessionService(sessionRepo, refreshRepo, repo, tokenManager)
+	service := services.NewSuperuserService(ctx, repo, totpManager, tokenManager, mailer, mailTemplates, policy, sessionService, passwordPolicy)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, repo, policy)
+	auditService := services.NewAuditService(auditRepo)
+	if configs.AuditCheckpointInterval > 0 {
+		auditService.StartCheckpoints(ctx, configs.AuditCheckpointInterval)
+	}
+	handler := handlers.NewSuperuserHandler(service, sessionService, apiKeyService, auditService, tokenManager)
+
+	// Cookie values are stored as configured by cookies.codec
+	cookieCodec, err := cookies.NewCodec()
+	if err != nil {
+		logging.Fatal("Failed to initiate cookie codec", "error", err)
+	}
+
+	// Rate limit buckets are kept in memory, which is enough for a single instance
+	rateLimitStore := middlewares.NewInMemoryRateLimitStore(ctx, configs.RateLimitShards, configs.RateLimitSweepInterval)
 
 	// Middleware and route registration
-	router.Use(middlewares.LoggingMiddleware())
 	router.Use(middlewares.RequestIDMiddleware())
+	router.Use(middlewares.LoggingMiddleware())
 	router.Use(middlewares.ResponseStrategyMiddleware())
-	routes.RegisterSuperuserRoutes(router, handler, tokenManager)
+	router.Use(middlewares.CookieMiddleware(cookieCodec))
+	router.Use(middlewares.CSRFMiddleware())
+	routes.RegisterSuperuserRoutes(router, handler, tokenManager, sessionService, sessionService, sessionService, apiKeyService, service, rateLimitStore)
 
 	// Start the Gin server
 	err = initializers.StartGinServer(router)
 	if err != nil {
-		log.Fatalf("Failed to start Gin server: %v", err)
+		logging.Fatal("Failed to start Gin server", "error", err)
 	}
 
 	// Handle graceful shutdown
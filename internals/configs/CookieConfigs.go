@@ -0,0 +1,118 @@
+package configs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// cookieKeyMinLength is the shortest cookies.key accepted by the signed and encrypted codecs.
+const cookieKeyMinLength = 32
+
+// CookiePolicy describes how one cookie is named, scoped and protected.
+type CookiePolicy struct {
+	Name     string
+	Domain   string
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// loadCookieConfig reads the shared cookie settings and the policy of every cookie the server sets.
+func loadCookieConfig() error {
+	secure, err := cookieSecure(viper.GetString("cookies.secure"))
+	if err != nil {
+		return err
+	}
+
+	CookieCodec = viper.GetString("cookies.codec")
+	switch CookieCodec {
+	case "", "none":
+		CookieCodec = "none"
+	case "signed", "encrypted":
+		CookieKey, err = secretValue("cookies.key", "HTMX_GO_COOKIES_KEY", cookieKeyMinLength)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid cookies.codec %q, must be none, signed or encrypted", CookieCodec)
+	}
+
+	for _, cookie := range []struct {
+		key    string
+		policy *CookiePolicy
+	}{
+		{"session", &SessionCookie},
+		{"refresh", &RefreshCookie},
+		{"mfa_pending", &MFAPendingCookie},
+		{"csrf", &CSRFCookie},
+	} {
+		policy, err := cookiePolicy(cookie.key, secure)
+		if err != nil {
+			return err
+		}
+		*cookie.policy = policy
+	}
+	return nil
+}
+
+// cookiePolicy builds the policy stored under cookies.<key>.
+func cookiePolicy(key string, secure bool) (CookiePolicy, error) {
+	policy := CookiePolicy{
+		Name:     viper.GetString(fmt.Sprintf("cookies.%s.name", key)),
+		Domain:   viper.GetString("cookies.domain"),
+		Path:     viper.GetString(fmt.Sprintf("cookies.%s.path", key)),
+		Secure:   secure,
+		HttpOnly: true,
+	}
+	if policy.Name == "" {
+		return policy, fmt.Errorf("cookies.%s.name is required", key)
+	}
+	if policy.Path == "" {
+		policy.Path = "/"
+	}
+
+	switch strings.ToLower(viper.GetString(fmt.Sprintf("cookies.%s.same_site", key))) {
+	case "", "lax":
+		policy.SameSite = http.SameSiteLaxMode
+	case "strict":
+		policy.SameSite = http.SameSiteStrictMode
+	case "none":
+		// Browsers drop SameSite=None cookies that are not Secure
+		if !secure {
+			return policy, fmt.Errorf("cookies.%s.same_site none requires secure cookies", key)
+		}
+		policy.SameSite = http.SameSiteNoneMode
+	default:
+		return policy, fmt.Errorf("invalid cookies.%s.same_site, must be lax, strict or none", key)
+	}
+
+	// __Host- cookies must be host-only with path /, anything else can still use __Secure-
+	if viper.GetBool("cookies.host_prefix") {
+		if !secure {
+			return policy, fmt.Errorf("cookies.host_prefix requires secure cookies")
+		}
+		if policy.Path == "/" && policy.Domain == "" {
+			policy.Name = "__Host-" + policy.Name
+		} else {
+			policy.Name = "__Secure-" + policy.Name
+		}
+	}
+	return policy, nil
+}
+
+// cookieSecure resolves cookies.secure, where auto follows the TLS setting.
+func cookieSecure(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "auto":
+		return UseTLS, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid cookies.secure %q, must be auto, true or false", value)
+}
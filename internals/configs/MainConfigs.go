@@ -2,32 +2,113 @@ package configs
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 var (
-	Port                int
-	UseTLS              bool
-	UseJWT              bool
-	UseCORS             bool
-	AllowedCredentials  bool
-	StaticPath          string
-	MongoDBUrl          string
-	TlsKeyFile          string
-	Environment         string
-	TlsCertFile         string
-	TemplatePath        string
-	TokenSymmetricKey   string
-	AllowedOrigins      []string
-	AllowedMethods      []string
-	AllowedHeaders      []string
-	ExposedHeaders      []string
-	TokenAccessDuration time.Duration
+	Port                    int
+	UseTLS                  bool
+	UseJWT                  bool
+	UseCORS                 bool
+	RequireEmailVerify      bool
+	SMTPPort                int
+	AllowedCredentials      bool
+	BaseURL                 string
+	StaticPath              string
+	SMTPServer              string
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	SMTPEncryption          string
+	MailBackend             string
+	MaildirPath             string
+	EmailTemplatePath       string
+	RBACDefaultRole         string
+	MongoDBUrl              string
+	TlsKeyFile              string
+	Environment             string
+	TlsCertFile             string
+	TemplatePath            string
+	TokenSymmetricKey       string
+	TOTPIssuer              string
+	TrustedProxies          []string
+	AllowedOrigins          []string
+	TokenLookup             []string
+	AllowedMethods          []string
+	AllowedHeaders          []string
+	ExposedHeaders          []string
+	RBACRoles               map[string][]string
+	RBACGroups              map[string][]string
+	TokenAccessDuration     time.Duration
+	TokenMFADuration        time.Duration
+	TokenResetDuration      time.Duration
+	TokenRefreshDuration    time.Duration
+	RefreshReuseGrace       time.Duration
+	SessionSweepInterval    time.Duration
+	SessionActivityInterval time.Duration
+	TokenSigning            string
+	KeyringPath             string
+	KeyRotationInterval     time.Duration
+	KeyRetireAfter          time.Duration
+	KeyRotationCheck        time.Duration
+	SMTPTimeout             time.Duration
+	TokenVerifyDuration     time.Duration
+	VerifyResendDelay       time.Duration
+	TOTPPeriod              uint
+	TOTPDigits              int
+	TOTPSkew                uint
+	TOTPRecoveryCodes       int
+	APIKeyMaxPerUser        int
+	LockoutThreshold        int
+	LockoutBaseDuration     time.Duration
+	LockoutMaxDuration      time.Duration
+	RateLimitEnabled        bool
+	RateLimitShards         int
+	RateLimitSweepInterval  time.Duration
+	RateLimitPolicies       map[string][]RateLimitRule
+	PasswordMinLength       int
+	PasswordMaxLength       int
+	PasswordRequireUpper    bool
+	PasswordRequireLower    bool
+	PasswordRequireDigit    bool
+	PasswordRequireSymbol   bool
+	PasswordRejectPersonal  bool
+	PasswordHistory         int
+	PasswordBreachedList    string
+	AuditRetention          time.Duration
+	AuditSweepInterval      time.Duration
+	AuditExportLimit        int
+	AuditChainKey           string
+	AuditCheckpointInterval time.Duration
+	LogFormat               string
+	LogLevel                slog.Level
+	LogDir                  string
+	LogMaxSize              int64
+	LogRotateInterval       time.Duration
+	LogMaxBackups           int
+	LogMaxAge               time.Duration
+	LogCompress             bool
+	CookieCodec             string
+	CookieKey               string
+	SessionCookie           CookiePolicy
+	RefreshCookie           CookiePolicy
+	MFAPendingCookie        CookiePolicy
+	CSRFCookie              CookiePolicy
 )
 
+// RateLimitRule allows Limit requests per Window for every client sharing a key.
+// By selects the key: the client IP, the account named in the request, or the authenticated user.
+type RateLimitRule struct {
+	By     string        `mapstructure:"by"`
+	Limit  int           `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+}
+
 // InitializeServerConfig initializes the server configuration using Viper.
 // It returns an error if any issues occur during the configuration setup.
 func InitializeServerConfig(configFile string) error {
@@ -43,10 +124,51 @@ func InitializeServerConfig(configFile string) error {
 	UseTLS = viper.GetBool("tls.use_tls")
 	UseJWT = viper.GetBool("token.use_jwt")
 	UseCORS = viper.GetBool("server.use_cors")
+	TrustedProxies = viper.GetStringSlice("server.trusted_proxies")
 	Environment = viper.GetString("application.config")
 	StaticPath = viper.GetString("application.static_path")
-	TokenSymmetricKey = viper.GetString("token.symmetric_key")
 	TemplatePath = viper.GetString("application.template_path")
+	BaseURL = viper.GetString("application.base_url")
+
+	// Load the logging settings
+	LogFormat = viper.GetString("logging.format")
+	if LogFormat != "json" && LogFormat != "text" {
+		return fmt.Errorf("invalid logging.format %q, must be json or text", LogFormat)
+	}
+	if err := LogLevel.UnmarshalText([]byte(viper.GetString("logging.level"))); err != nil {
+		return fmt.Errorf("invalid logging.level: %w", err)
+	}
+	LogDir = viper.GetString("logging.dir")
+	LogMaxSize = viper.GetInt64("logging.max_size") * 1024 * 1024
+	LogRotateInterval = viper.GetDuration("logging.rotate_interval")
+	LogMaxBackups = viper.GetInt("logging.max_backups")
+	LogMaxAge = viper.GetDuration("logging.max_age")
+	LogCompress = viper.GetBool("logging.compress")
+	if LogDir == "" {
+		return fmt.Errorf("logging.dir must be set")
+	}
+	if LogMaxSize < 0 || LogRotateInterval < 0 || LogMaxBackups < 0 || LogMaxAge < 0 {
+		return fmt.Errorf("invalid logging settings, max_size, rotate_interval, max_backups and max_age must not be negative")
+	}
+
+	// Load outgoing mail settings
+	SMTPServer = viper.GetString("smtp.server")
+	SMTPPort = viper.GetInt("smtp.port")
+	SMTPUsername = viper.GetString("smtp.username")
+	SMTPPassword = viper.GetString("smtp.password")
+	SMTPFrom = viper.GetString("smtp.from")
+	SMTPEncryption = viper.GetString("smtp.encryption")
+	MailBackend = viper.GetString("smtp.backend")
+	MaildirPath = viper.GetString("smtp.maildir_path")
+	EmailTemplatePath = viper.GetString("smtp.template_path")
+	SMTPTimeout = viper.GetDuration("smtp.timeout")
+
+	// Load two-factor authentication settings
+	TOTPIssuer = viper.GetString("totp.issuer")
+	TOTPPeriod = viper.GetUint("totp.period")
+	TOTPDigits = viper.GetInt("totp.digits")
+	TOTPSkew = viper.GetUint("totp.skew")
+	TOTPRecoveryCodes = viper.GetInt("totp.recovery_codes")
 
 	// Load environment-specific configurations
 	loadEnvironmentConfig(Environment)
@@ -59,11 +181,148 @@ func InitializeServerConfig(configFile string) error {
 		return fmt.Errorf("invalid duration for token.access_duration: %w", err)
 	}
 
-	log.Printf("Token access duration set to: %s", TokenAccessDuration.String())
-	log.Printf("Server is being initiated with %s environment", Environment)
+	// Parse the lifetime of the pending token issued between password and 2FA checks
+	TokenMFADuration, err = time.ParseDuration(viper.GetString("token.mfa_pending_duration"))
+	if err != nil {
+		return fmt.Errorf("invalid duration for token.mfa_pending_duration: %w", err)
+	}
+
+	// Parse the lifetime of password reset tokens
+	TokenResetDuration, err = time.ParseDuration(viper.GetString("token.reset_duration"))
+	if err != nil {
+		return fmt.Errorf("invalid duration for token.reset_duration: %w", err)
+	}
+
+	// Parse the lifetime of refresh tokens and the window in which a rotated one may still be replayed
+	TokenRefreshDuration, err = time.ParseDuration(viper.GetString("token.refresh_duration"))
+	if err != nil {
+		return fmt.Errorf("invalid duration for token.refresh_duration: %w", err)
+	}
+	RefreshReuseGrace = viper.GetDuration("token.refresh_reuse_grace")
+	SessionSweepInterval = viper.GetDuration("session.sweep_interval")
+	SessionActivityInterval = viper.GetDuration("session.activity_interval")
+
+	// Load where access tokens are read from, in order of precedence
+	TokenLookup = viper.GetStringSlice("token.lookup")
+	if len(TokenLookup) == 0 {
+		TokenLookup = []string{"header", "cookie"}
+	}
+	for _, source := range TokenLookup {
+		if source != "header" && source != "cookie" {
+			return fmt.Errorf("invalid token.lookup source %q, must be header or cookie", source)
+		}
+	}
+
+	// Load the token signing mode and the keyring used for asymmetric signing
+	TokenSigning = viper.GetString("token.signing")
+	if TokenSigning == "" || TokenSigning == "symmetric" {
+		// HS256 and PASETO v2.local both need 32 bytes, PASETO exactly that many
+		TokenSymmetricKey, err = secretValue("token.symmetric_key", "HTMX_GO_TOKEN_SYMMETRIC_KEY", 32)
+		if err != nil {
+			return err
+		}
+	}
+	KeyringPath = viper.GetString("token.keyring.path")
+	KeyRotationInterval = viper.GetDuration("token.keyring.rotation_interval")
+	KeyRetireAfter = viper.GetDuration("token.keyring.retire_after")
+	KeyRotationCheck = viper.GetDuration("token.keyring.check_interval")
+
+	// Load the failed login lockout settings
+	LockoutThreshold = viper.GetInt("lockout.threshold")
+	LockoutBaseDuration = viper.GetDuration("lockout.base_duration")
+	LockoutMaxDuration = viper.GetDuration("lockout.max_duration")
+
+	// Load the rate limit policies, routes refer to them by name
+	RateLimitEnabled = viper.GetBool("rate_limit.enabled")
+	RateLimitShards = viper.GetInt("rate_limit.shards")
+	RateLimitSweepInterval = viper.GetDuration("rate_limit.sweep_interval")
+	RateLimitPolicies = map[string][]RateLimitRule{}
+	if err := viper.UnmarshalKey("rate_limit.policies", &RateLimitPolicies); err != nil {
+		return fmt.Errorf("invalid rate_limit.policies: %w", err)
+	}
+	for name, rules := range RateLimitPolicies {
+		for _, rule := range rules {
+			if rule.By != "ip" && rule.By != "account" && rule.By != "user" {
+				return fmt.Errorf("invalid rate_limit.policies.%s key %q, must be ip, account or user", name, rule.By)
+			}
+			if rule.Limit <= 0 || rule.Window <= 0 {
+				return fmt.Errorf("rate_limit.policies.%s needs a positive limit and window", name)
+			}
+		}
+	}
+
+	// Load the password policy
+	PasswordMinLength = viper.GetInt("password_policy.min_length")
+	PasswordMaxLength = viper.GetInt("password_policy.max_length")
+	PasswordRequireUpper = viper.GetBool("password_policy.require_upper")
+	PasswordRequireLower = viper.GetBool("password_policy.require_lower")
+	PasswordRequireDigit = viper.GetBool("password_policy.require_digit")
+	PasswordRequireSymbol = viper.GetBool("password_policy.require_symbol")
+	PasswordRejectPersonal = viper.GetBool("password_policy.reject_personal_info")
+	PasswordHistory = viper.GetInt("password_policy.history")
+	PasswordBreachedList = viper.GetString("password_policy.breached_list")
+	// bcrypt ignores everything after 72 bytes, longer passwords would be silently truncated
+	if PasswordMaxLength <= 0 || PasswordMaxLength > 72 {
+		return fmt.Errorf("password_policy.max_length must be between 1 and 72")
+	}
+	if PasswordMinLength > PasswordMaxLength || PasswordHistory < 0 {
+		return fmt.Errorf("invalid password_policy, min_length must not exceed max_length and history must not be negative")
+	}
+
+	// Load the cookie policies
+	if err := loadCookieConfig(); err != nil {
+		return err
+	}
+
+	// Load the audit log settings
+	AuditRetention = viper.GetDuration("audit.retention")
+	AuditSweepInterval = viper.GetDuration("audit.sweep_interval")
+	AuditExportLimit = viper.GetInt("audit.export_limit")
+	if AuditExportLimit <= 0 {
+		return fmt.Errorf("audit.export_limit must be positive")
+	}
+	AuditChainKey, err = secretValue("audit.chain_key", "HTMX_GO_AUDIT_CHAIN_KEY", 32)
+	if err != nil {
+		return err
+	}
+	AuditCheckpointInterval = viper.GetDuration("audit.checkpoint_interval")
+
+	// Load the personal API key settings
+	APIKeyMaxPerUser = viper.GetInt("api_keys.max_per_user")
+
+	// Load role-based access control settings
+	RBACDefaultRole = viper.GetString("rbac.default_role")
+	RBACRoles = viper.GetStringMapStringSlice("rbac.roles")
+	RBACGroups = viper.GetStringMapStringSlice("rbac.groups")
+
+	// Load email verification settings
+	RequireEmailVerify = viper.GetBool("email_verification.required")
+	VerifyResendDelay = viper.GetDuration("email_verification.resend_interval")
+	TokenVerifyDuration, err = time.ParseDuration(viper.GetString("email_verification.token_duration"))
+	if err != nil {
+		return fmt.Errorf("invalid duration for email_verification.token_duration: %w", err)
+	}
+
+	slog.Info("Configuration loaded", "environment", Environment, "token_access_duration", TokenAccessDuration.String())
 	return nil
 }
 
+// secretValue reads a secret from the environment variable env, falling back to the config key.
+// It must be at least minLength characters and must not be the placeholder shipped in config.yaml.
+func secretValue(key, env string, minLength int) (string, error) {
+	value := os.Getenv(env)
+	if value == "" {
+		value = viper.GetString(key)
+	}
+	if value == "" || strings.HasPrefix(strings.ToLower(value), "change-me") {
+		return "", fmt.Errorf("%s is not set, set %s or %s to a random secret of at least %d characters", key, env, key, minLength)
+	}
+	if len(value) < minLength {
+		return "", fmt.Errorf("%s must be at least %d characters", key, minLength)
+	}
+	return value, nil
+}
+
 // loadEnvironmentConfig loads the server configuration for the given environment.
 func loadEnvironmentConfig(env string) {
 	TlsKeyFile = viper.GetString(fmt.Sprintf("%s.key_file", env))
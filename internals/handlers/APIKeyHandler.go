@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+)
+
+// APIKeysListHandler lists the superuser's API keys with their scopes and usage, and the scopes a new key may get.
+func (h *SuperuserHandler) APIKeysListHandler(c *gin.Context) {
+	h.renderAPIKeys(c, "", http.StatusOK)
+}
+
+// APIKeyCreateHandler creates an API key. The key is only part of this response and cannot be shown again.
+func (h *SuperuserHandler) APIKeyCreateHandler(c *gin.Context) {
+	var request struct {
+		Name   string   `form:"name" json:"name" binding:"required,max=64"`
+		Scopes []string `form:"scopes" json:"scopes"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "api_key_created.html", "A name of at most 64 characters is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "api_key_created.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	key, plainKey, err := h.apiKeys.CreateAPIKey(c.Request.Context(), userID, strings.TrimSpace(request.Name), request.Scopes)
+	if errors.Is(err, services.ErrAPIKeyScope) || errors.Is(err, services.ErrAPIKeyLimit) {
+		h.handleError(c, "api_key_created.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.handleError(c, "api_key_created.html", "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	h.recordAudit(c, userID, services.AuditAPIKeyCreated, map[string]interface{}{"api_key_id": key.ID.String(), "name": key.Name, "scopes": key.Scopes})
+
+	// Lets the API keys panel reload itself
+	c.Header("HX-Trigger", "apiKeysChanged")
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template": "api_key_created.html",
+		"message":  "API key created. Copy it now, it will not be shown again.",
+		"api_key":  key,
+		"key":      plainKey,
+	}, http.StatusCreated)
+}
+
+// APIKeyRevokeHandler revokes one of the superuser's API keys and re-renders the API keys panel.
+func (h *SuperuserHandler) APIKeyRevokeHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "api_keys_panel.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.renderAPIKeys(c, "Invalid API key ID format", http.StatusBadRequest)
+		return
+	}
+
+	err = h.apiKeys.RevokeAPIKey(c.Request.Context(), userID, keyID)
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		h.renderAPIKeys(c, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.renderAPIKeys(c, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	h.recordAudit(c, userID, services.AuditAPIKeyRevoked, map[string]interface{}{"api_key_id": keyID.String()})
+
+	h.renderAPIKeys(c, "", http.StatusOK)
+}
+
+// renderAPIKeys responds with the API keys panel, optionally showing an error above it.
+func (h *SuperuserHandler) renderAPIKeys(c *gin.Context, errorMessage string, statusCode int) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "api_keys_panel.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "api_keys_panel.html", "Failed to load API keys", http.StatusInternalServerError)
+		return
+	}
+	scopes, err := h.apiKeys.AvailableScopes(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "api_keys_panel.html", "Failed to load API keys", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"template": "api_keys_panel.html",
+		"api_keys": keys,
+		"scopes":   scopes,
+	}
+	if errorMessage != "" {
+		data["error"] = errorMessage
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, data, statusCode)
+}
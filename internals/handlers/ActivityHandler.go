@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+// activityPageSize is the number of timeline entries loaded per scroll step.
+const activityPageSize = 15
+
+// activityDayLayout formats the day headings of the timeline.
+const activityDayLayout = "Monday, 2 January 2006"
+
+// activityLabels describes audited actions from the superuser's point of view.
+var activityLabels = map[string]string{
+	services.AuditLogin:                  "Signed in",
+	services.AuditLoginFailed:            "Failed sign-in attempt",
+	services.AuditLogout:                 "Signed out",
+	services.AuditProfileUpdated:         "Profile updated",
+	services.AuditPasswordChanged:        "Password changed",
+	services.AuditPasswordResetRequested: "Password reset requested",
+	services.AuditPasswordReset:          "Password reset",
+	services.AuditRoleChanged:            "Changed a superuser's role",
+	services.AuditSuperuserArchived:      "Archived a superuser",
+	services.AuditSuperuserRestored:      "Restored a superuser",
+	services.AuditSuperuserUnlocked:      "Unlocked a superuser",
+	services.AuditSuperuserDeleted:       "Deleted a superuser",
+	services.AuditAPIKeyCreated:          "API key created",
+	services.AuditAPIKeyRevoked:          "API key revoked",
+	services.AuditTwoFactorEnabled:       "Two-factor authentication enabled",
+	services.AuditRecoveryCodesRenewed:   "Recovery codes regenerated",
+	services.AuditFileUploaded:           "File uploaded",
+	services.AuditFileDownloaded:         "File downloaded",
+}
+
+// activityTargetLabels describes admin actions from the point of view of the superuser they were taken on.
+var activityTargetLabels = map[string]string{
+	services.AuditRoleChanged:       "Role changed by an administrator",
+	services.AuditSuperuserArchived: "Account archived by an administrator",
+	services.AuditSuperuserRestored: "Account restored by an administrator",
+	services.AuditSuperuserUnlocked: "Account unlocked by an administrator",
+}
+
+// activityEntry is one timeline item.
+type activityEntry struct {
+	ID        string                 `json:"id"`
+	Action    string                 `json:"action"`
+	Label     string                 `json:"label"`
+	Timestamp time.Time              `json:"timestamp"`
+	Time      string                 `json:"-"`
+	IPAddress string                 `json:"ip_address"`
+	UserAgent string                 `json:"user_agent"`
+	Unusual   []string               `json:"unusual,omitempty"` // reasons the entry is highlighted
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// activityDay groups the timeline entries of one UTC day.
+type activityDay struct {
+	Date    string          `json:"date"`
+	Heading string          `json:"heading"`
+	Entries []activityEntry `json:"entries"`
+}
+
+// ActivityTimelineHandler renders the logged-in superuser's activity, newest first and grouped by day.
+// Each page ends with a loader that fetches the next one when it scrolls into view. The first page pins
+// the before time, so entries recorded while scrolling do not shift the pages.
+func (h *SuperuserHandler) ActivityTimelineHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	before := time.Now().UTC()
+	if value := c.Query("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			h.handleError(c, "activity_timeline.html", "Invalid before time", http.StatusBadRequest)
+			return
+		}
+		before = parsed
+	}
+
+	entries, hasNext, err := h.audit.ListUserActivity(c.Request.Context(), currentUserID(c), before, page, activityPageSize)
+	if err != nil {
+		h.handleError(c, "activity_timeline.html", "Failed to load activity", http.StatusInternalServerError)
+		return
+	}
+
+	// A day that continues from the previous page does not get a second heading
+	days := groupActivityByDay(entries, currentUserID(c))
+	lastDay := c.Query("last_day")
+	continued := len(days) > 0 && days[0].Date == lastDay
+	if len(days) > 0 {
+		lastDay = days[len(days)-1].Date
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":  "activity_timeline.html",
+		"days":      days,
+		"continued": continued,
+		"before":    before.Format(time.RFC3339Nano),
+		"last_day":  lastDay,
+		"page":      page,
+		"next_page": page + 1,
+		"has_next":  hasNext,
+	}, http.StatusOK)
+}
+
+// groupActivityByDay turns audit log entries, newest first, into timeline days for userID.
+// Actions an administrator took on the account do not show the administrator's client or metadata.
+func groupActivityByDay(entries []*types.UserActivityLog, userID uuid.UUID) []activityDay {
+	days := make([]activityDay, 0)
+	for _, entry := range entries {
+		timestamp := entry.Timestamp.UTC()
+		date := timestamp.Format(auditDateLayout)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, activityDay{Date: date, Heading: timestamp.Format(activityDayLayout)})
+		}
+
+		// Actions taken on the account by an administrator
+		if entry.UserID != userID {
+			label, ok := activityTargetLabels[entry.Action]
+			if !ok {
+				label = entry.Action
+			}
+			days[len(days)-1].Entries = append(days[len(days)-1].Entries, activityEntry{
+				ID:        entry.ID.String(),
+				Action:    entry.Action,
+				Label:     label,
+				Timestamp: timestamp,
+				Time:      timestamp.Format("15:04"),
+			})
+			continue
+		}
+
+		label, ok := activityLabels[entry.Action]
+		if !ok {
+			label = entry.Action
+		}
+		item := activityEntry{
+			ID:        entry.ID.String(),
+			Action:    entry.Action,
+			Label:     label,
+			Timestamp: timestamp,
+			Time:      timestamp.Format("15:04"),
+			IPAddress: entry.IPAddress,
+			UserAgent: entry.UserAgent,
+			Metadata:  entry.Metadata,
+		}
+		if flagged, _ := entry.Metadata["new_ip"].(bool); flagged {
+			item.Unusual = append(item.Unusual, "New IP address")
+		}
+		if flagged, _ := entry.Metadata["new_device"].(bool); flagged {
+			item.Unusual = append(item.Unusual, "New device")
+		}
+
+		days[len(days)-1].Entries = append(days[len(days)-1].Entries, item)
+	}
+	return days
+}
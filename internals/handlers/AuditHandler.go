@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// auditDateLayout is the format of the from and to filters, both are whole days in UTC.
+const auditDateLayout = "2006-01-02"
+
+// AuditLogsHandler renders the paginated, filterable audit log. HTMX requests targeting the table
+// only receive the table fragment.
+func (h *SuperuserHandler) AuditLogsHandler(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, "admin_error.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(adminPageSize)))
+	if pageSize < 1 || pageSize > adminMaxPageSize {
+		pageSize = adminPageSize
+	}
+
+	entries, hasNext, err := h.audit.ListAuditLogsPage(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+
+	template := "audit_logs.html"
+	if c.GetHeader("HX-Target") == "audit-table" {
+		template = "audit_logs_table.html"
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":  template,
+		"title":     "Audit Log",
+		"entries":   entries,
+		"actions":   services.AuditActions(),
+		"filter":    auditFilterValues(c),
+		"page":      page,
+		"page_size": pageSize,
+		"prev_page": page - 1,
+		"next_page": page + 1,
+		"has_next":  hasNext,
+	}, http.StatusOK)
+}
+
+// AuditExportHandler downloads the audit log entries matching the filters as CSV or JSON.
+func (h *SuperuserHandler) AuditExportHandler(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, "admin_error.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		h.handleError(c, "admin_error.html", "Export format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.audit.ExportAuditLogs(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Failed to export audit log", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "timestamp", "user_id", "action", "ip_address", "user_agent", "metadata"})
+	for _, entry := range entries {
+		metadata := ""
+		if len(entry.Metadata) > 0 {
+			encoded, _ := json.Marshal(entry.Metadata)
+			metadata = string(encoded)
+		}
+		writer.Write([]string{
+			entry.ID.String(),
+			entry.Timestamp.UTC().Format(time.RFC3339),
+			entry.UserID.String(),
+			entry.Action,
+			csvSafe(entry.IPAddress),
+			csvSafe(entry.UserAgent),
+			csvSafe(metadata),
+		})
+	}
+	writer.Flush()
+}
+
+// AuditVerifyHandler verifies the audit log hash chain and reports the first broken link, if any.
+func (h *SuperuserHandler) AuditVerifyHandler(c *gin.Context) {
+	report, err := h.audit.VerifyAuditChain(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to verify audit log chain", "error", err)
+		h.handleError(c, "admin_error.html", "Failed to verify audit log", http.StatusInternalServerError)
+		return
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template": "audit_verify.html",
+		"report":   report,
+	}, http.StatusOK)
+}
+
+// recordAudit writes an audit log entry for the current request.
+// A failure is logged but never fails the request that is being audited.
+func (h *SuperuserHandler) recordAudit(c *gin.Context, userID uuid.UUID, action string, metadata map[string]interface{}) {
+	if err := h.audit.Record(c.Request.Context(), userID, action, c.ClientIP(), c.Request.UserAgent(), metadata); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to record audit log entry", "action", action, "superuser_id", userID, "error", err)
+	}
+}
+
+// currentUserID returns the authenticated superuser's ID, or uuid.Nil on public routes.
+func currentUserID(c *gin.Context) uuid.UUID {
+	userID, _ := uuid.Parse(c.GetString("userID"))
+	return userID
+}
+
+// loginFailureReason names why a login failed, for the audit log only.
+func loginFailureReason(err error) string {
+	var locked *services.AccountLockedError
+	switch {
+	case errors.As(err, &locked):
+		return "locked"
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return "email_not_verified"
+	}
+	return "invalid_credentials"
+}
+
+// auditFilterFromQuery reads the audit log filters shared by the viewer and the export.
+func auditFilterFromQuery(c *gin.Context) (types.UserActivityLogFilter, error) {
+	filter := types.UserActivityLogFilter{
+		Action:    c.Query("action"),
+		IPAddress: strings.TrimSpace(c.Query("ip")),
+	}
+
+	if userID := strings.TrimSpace(c.Query("user_id")); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return filter, errors.New("Invalid user ID format")
+		}
+		filter.UserID = id
+	}
+	if from := c.Query("from"); from != "" {
+		day, err := time.Parse(auditDateLayout, from)
+		if err != nil {
+			return filter, errors.New("From must be a date like 2024-01-31")
+		}
+		filter.From = day
+	}
+	// The to date is inclusive, so the filter ends at the start of the next day
+	if to := c.Query("to"); to != "" {
+		day, err := time.Parse(auditDateLayout, to)
+		if err != nil {
+			return filter, errors.New("To must be a date like 2024-01-31")
+		}
+		filter.To = day.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// auditFilterValues echoes the filters back so the form keeps them.
+func auditFilterValues(c *gin.Context) map[string]string {
+	return map[string]string{
+		"user_id": c.Query("user_id"),
+		"action":  c.Query("action"),
+		"ip":      c.Query("ip"),
+		"from":    c.Query("from"),
+		"to":      c.Query("to"),
+	}
+}
+
+// csvSafe stops spreadsheet applications from evaluating user supplied values as formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
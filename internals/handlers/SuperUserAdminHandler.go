@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+)
+
+// adminPageSize is the default number of superusers shown per admin table page.
+const adminPageSize = 20
+
+// adminMaxPageSize caps the page_size query parameter.
+const adminMaxPageSize = 100
+
+// AdminListSuperusersHandler renders the paginated superuser table. It also serves live search:
+// HTMX requests targeting the table only receive the table fragment.
+func (h *SuperuserHandler) AdminListSuperusersHandler(c *gin.Context) {
+	query := c.Query("q")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(adminPageSize)))
+	if pageSize < 1 || pageSize > adminMaxPageSize {
+		pageSize = adminPageSize
+	}
+
+	superusers, hasNext, err := h.service.ListSuperusersPage(c.Request.Context(), query, page, pageSize)
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Failed to load superusers", http.StatusInternalServerError)
+		return
+	}
+
+	template := "admin_superusers.html"
+	if c.GetHeader("HX-Target") == "superusers-table" {
+		template = "admin_superusers_table.html"
+	}
+
+	data := map[string]interface{}{
+		"template":   template,
+		"title":      "Manage Superusers",
+		"superusers": superusers,
+		"roles":      h.service.ListRoles(),
+		"query":      query,
+		"page":       page,
+		"page_size":  pageSize,
+		"prev_page":  page - 1,
+		"next_page":  page + 1,
+		"has_next":   hasNext,
+	}
+
+	// Row templates need each superuser paired with the role options, JSON clients don't
+	strategy := responses.GetResponseStrategy(c)
+	if _, isHTML := strategy.(*responses.HTMLResponseStrategy); isHTML {
+		data["rows"] = h.adminRows(superusers)
+	}
+	strategy.Respond(c, data, http.StatusOK)
+}
+
+// AdminUpdateRoleHandler changes the role of a single superuser and returns the updated row.
+func (h *SuperuserHandler) AdminUpdateRoleHandler(c *gin.Context) {
+	var request struct {
+		Role string `form:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "admin_error.html", "Role is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateRole(c.Request.Context(), currentUserID(c), userID, request.Role); err != nil {
+		h.handleAdminError(c, err, err.Error())
+		return
+	}
+	h.recordAudit(c, currentUserID(c), services.AuditRoleChanged, map[string]interface{}{"target_ids": []string{userID.String()}, "role": request.Role})
+
+	h.respondAdminRow(c, userID)
+}
+
+// AdminArchiveSuperuserHandler soft deletes a superuser and returns the updated row.
+func (h *SuperuserHandler) AdminArchiveSuperuserHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ArchiveSuperuser(c.Request.Context(), currentUserID(c), userID); err != nil {
+		h.handleAdminError(c, err, "Failed to archive superuser")
+		return
+	}
+	h.recordAudit(c, currentUserID(c), services.AuditSuperuserArchived, map[string]interface{}{"target_ids": []string{userID.String()}})
+
+	h.respondAdminRow(c, userID)
+}
+
+// AdminRestoreSuperuserHandler reactivates an archived superuser and returns the updated row.
+func (h *SuperuserHandler) AdminRestoreSuperuserHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RestoreSuperuser(c.Request.Context(), currentUserID(c), userID); err != nil {
+		h.handleAdminError(c, err, "Failed to restore superuser")
+		return
+	}
+	h.recordAudit(c, currentUserID(c), services.AuditSuperuserRestored, map[string]interface{}{"target_ids": []string{userID.String()}})
+
+	h.respondAdminRow(c, userID)
+}
+
+// AdminUnlockSuperuserHandler lifts a failed login lockout and returns the updated row.
+func (h *SuperuserHandler) AdminUnlockSuperuserHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnlockSuperuser(c.Request.Context(), currentUserID(c), userID); err != nil {
+		h.handleAdminError(c, err, "Failed to unlock superuser")
+		return
+	}
+	h.recordAudit(c, currentUserID(c), services.AuditSuperuserUnlocked, map[string]interface{}{"target_ids": []string{userID.String()}})
+
+	h.respondAdminRow(c, userID)
+}
+
+// AdminDeleteSuperuserHandler permanently deletes an archived superuser.
+func (h *SuperuserHandler) AdminDeleteSuperuserHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSuperuser(c.Request.Context(), currentUserID(c), userID); err != nil {
+		h.handleAdminError(c, err, err.Error())
+		return
+	}
+	h.recordAudit(c, currentUserID(c), services.AuditSuperuserDeleted, map[string]interface{}{"target_ids": []string{userID.String()}})
+
+	// The empty fragment removes the row when swapped with outerHTML
+	h.handleSuccess(c, "admin_empty.html", "Superuser deleted", http.StatusOK)
+}
+
+// AdminBulkActionHandler applies archive, restore or role changes to the selected superusers.
+func (h *SuperuserHandler) AdminBulkActionHandler(c *gin.Context) {
+	var request struct {
+		IDs    []string `form:"ids" binding:"required"`
+		Action string   `form:"action" binding:"required"`
+		Role   string   `form:"role"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "admin_error.html", "Select at least one superuser and an action", http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(request.IDs))
+	for _, id := range request.IDs {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			h.handleError(c, "admin_error.html", "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, userID)
+	}
+
+	// Only whitelisted fields may be changed in bulk
+	var updates map[string]interface{}
+	var action string
+	switch request.Action {
+	case "archive":
+		updates = map[string]interface{}{"archived": true}
+		action = services.AuditSuperuserArchived
+	case "restore":
+		updates = map[string]interface{}{"archived": false}
+		action = services.AuditSuperuserRestored
+	case "set_role":
+		if !h.hasPermission(c, rbac.PermRolesManage) {
+			h.handleError(c, "forbidden.html", "You do not have permission to perform this action", http.StatusForbidden)
+			return
+		}
+		updates = map[string]interface{}{"role": request.Role}
+		action = services.AuditRoleChanged
+	default:
+		h.handleError(c, "admin_error.html", "Unknown bulk action", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.BulkUpdateSuperusers(c.Request.Context(), currentUserID(c), ids, updates); err != nil {
+		h.handleAdminError(c, err, err.Error())
+		return
+	}
+	targetIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		targetIDs = append(targetIDs, id.String())
+	}
+	metadata := map[string]interface{}{"target_ids": targetIDs}
+	if request.Action == "set_role" {
+		metadata["role"] = request.Role
+	}
+	h.recordAudit(c, currentUserID(c), action, metadata)
+
+	// Re-render the table so every changed row is refreshed
+	c.Request.Header.Set("HX-Target", "superusers-table")
+	h.AdminListSuperusersHandler(c)
+}
+
+// RoleManagementHandler lists the configured roles and the permissions each one grants.
+func (h *SuperuserHandler) RoleManagementHandler(c *gin.Context) {
+	roles := make([]map[string]interface{}, 0)
+	for _, role := range h.service.ListRoles() {
+		roles = append(roles, map[string]interface{}{
+			"name":        role,
+			"permissions": h.service.RolePermissions(role),
+		})
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template": "admin_roles.html",
+		"title":    "Roles",
+		"roles":    roles,
+	}, http.StatusOK)
+}
+
+// respondAdminRow re-renders a single table row after a row-level action.
+func (h *SuperuserHandler) respondAdminRow(c *gin.Context, userID uuid.UUID) {
+	superuser, err := h.service.GetSuperuser(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "admin_error.html", "Superuser not found", http.StatusNotFound)
+		return
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":     "admin_superuser_row.html",
+		"superuser":    superuser,
+		"roles":        h.service.ListRoles(),
+		"locked_until": lockedUntil(superuser),
+		"last_login":   lastLogin(superuser),
+	}, http.StatusOK)
+}
+
+// adminRows pairs every superuser with the role options so the row template can render them.
+func (h *SuperuserHandler) adminRows(superusers []*types.SuperUserType) []map[string]interface{} {
+	roles := h.service.ListRoles()
+	rows := make([]map[string]interface{}, 0, len(superusers))
+	for _, superuser := range superusers {
+		rows = append(rows, map[string]interface{}{
+			"superuser":    superuser,
+			"roles":        roles,
+			"locked_until": lockedUntil(superuser),
+			"last_login":   lastLogin(superuser),
+		})
+	}
+	return rows
+}
+
+// lockedUntil formats the end of a superuser's lockout, or returns an empty string if logins are allowed.
+func lockedUntil(superuser *types.SuperUserType) string {
+	if superuser.LockedUntil <= time.Now().Unix() {
+		return ""
+	}
+	return time.Unix(superuser.LockedUntil, 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+// lastLogin formats the time of a superuser's last login, or returns an empty string if there was none.
+func lastLogin(superuser *types.SuperUserType) string {
+	if superuser.LastLoginAt == 0 {
+		return ""
+	}
+	return time.Unix(superuser.LastLoginAt, 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+// handleAdminError responds to a failed row or bulk action. Refusals to manage an account are forbidden,
+// anything else is reported with the given message.
+func (h *SuperuserHandler) handleAdminError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrManageSelf), errors.Is(err, services.ErrManageHigher):
+		h.handleError(c, "forbidden.html", err.Error(), http.StatusForbidden)
+	default:
+		h.handleError(c, "admin_error.html", message, http.StatusBadRequest)
+	}
+}
+
+// hasPermission reports whether the caller holds a permission, using the set cached by RequirePermission.
+func (h *SuperuserHandler) hasPermission(c *gin.Context, permission rbac.Permission) bool {
+	permissions, ok := c.Value("permissions").(rbac.PermissionSet)
+	return ok && permissions.Has(permission)
+}
@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
@@ -9,17 +15,29 @@ import (
 	"github.com/lordofthemind/htmx_GO/internals/configs"
 	"github.com/lordofthemind/htmx_GO/internals/responses"
 	"github.com/lordofthemind/htmx_GO/internals/services"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/cookies"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+	"github.com/lordofthemind/htmx_GO/pkgs/middlewares"
+	"github.com/lordofthemind/htmx_GO/pkgs/passwords"
 	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+	"github.com/lordofthemind/htmx_GO/pkgs/twofactor"
 )
 
 type SuperuserHandler struct {
 	service      services.SuperuserService
+	sessions     services.SessionService
+	apiKeys      services.APIKeyService
+	audit        services.AuditService
 	tokenManager tokens.TokenManager
 }
 
-func NewSuperuserHandler(service services.SuperuserService, tokenManager tokens.TokenManager) *SuperuserHandler {
+func NewSuperuserHandler(service services.SuperuserService, sessions services.SessionService, apiKeys services.APIKeyService, audit services.AuditService, tokenManager tokens.TokenManager) *SuperuserHandler {
 	return &SuperuserHandler{
 		service:      service,
+		sessions:     sessions,
+		apiKeys:      apiKeys,
+		audit:        audit,
 		tokenManager: tokenManager,
 	}
 }
@@ -33,6 +51,23 @@ func (h *SuperuserHandler) handleError(c *gin.Context, template string, errorMes
 	}, statusCode)
 }
 
+// handlePasswordError responds to a rejected password with every broken rule of the password policy.
+// It returns false for any other error, which the caller handles itself.
+func (h *SuperuserHandler) handlePasswordError(c *gin.Context, template string, err error) bool {
+	var policyErr *passwords.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":   template,
+		"error":      "Password does not meet the requirements",
+		"violations": policyErr.Violations,
+	}, http.StatusBadRequest)
+	return true
+}
+
 // Centralized success response handling
 func (h *SuperuserHandler) handleSuccess(c *gin.Context, template string, message string, statusCode int) {
 	strategy := responses.GetResponseStrategy(c)
@@ -53,8 +88,9 @@ func (h *SuperuserHandler) IndexRender(c *gin.Context) {
 func (h *SuperuserHandler) RegisterRender(c *gin.Context) {
 	strategy := responses.GetResponseStrategy(c)
 	strategy.Respond(c, map[string]interface{}{
-		"template": "register.html",
-		"title":    "Register",
+		"template":            "register.html",
+		"title":               "Register",
+		"password_min_length": configs.PasswordMinLength,
 	}, http.StatusOK)
 }
 
@@ -70,7 +106,7 @@ func (h *SuperuserHandler) RegisterSuperuserHandler(c *gin.Context) {
 	var request struct {
 		Username string `form:"username" binding:"required"`
 		Email    string `form:"email" binding:"required,email"`
-		Password string `form:"password" binding:"required,min=6"`
+		Password string `form:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBind(&request); err != nil {
@@ -83,12 +119,46 @@ func (h *SuperuserHandler) RegisterSuperuserHandler(c *gin.Context) {
 	}
 
 	err := h.service.RegisterSuperuser(c.Request.Context(), request.Username, request.Email, request.Password)
+	if h.handlePasswordError(c, "register_error.html", err) {
+		return
+	}
 	if err != nil {
 		h.handleError(c, "register_error.html", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.handleSuccess(c, "register_success.html", "Superuser registered successfully", http.StatusOK)
+	h.handleSuccess(c, "register_success.html", "Superuser registered successfully. Check your email to verify your address.", http.StatusOK)
+}
+
+// VerifyEmailHandler confirms an email address from the signed link sent at registration.
+func (h *SuperuserHandler) VerifyEmailHandler(c *gin.Context) {
+	err := h.service.VerifyEmail(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.handleError(c, "verify_email.html", "Verification link is invalid or has expired", http.StatusBadRequest)
+		return
+	}
+
+	h.handleSuccess(c, "verify_email.html", "Email address verified successfully", http.StatusOK)
+}
+
+// VerifyEmailResendHandler sends a new verification link to an unverified address.
+func (h *SuperuserHandler) VerifyEmailResendHandler(c *gin.Context) {
+	var request struct {
+		Email string `form:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "verify_email_resend.html", "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	// Failures are only logged, only a registered address can fail and the response must not tell
+	if err := h.service.ResendVerificationEmail(c.Request.Context(), request.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to resend verification email", "error", err)
+	}
+
+	// Same message whether or not the email exists to avoid leaking registered addresses
+	h.handleSuccess(c, "verify_email_resend.html", "If an unverified account exists for that email, a new verification link has been sent", http.StatusOK)
 }
 
 func (h *SuperuserHandler) LoginSuperuserHandler(c *gin.Context) {
@@ -108,28 +178,291 @@ func (h *SuperuserHandler) LoginSuperuserHandler(c *gin.Context) {
 
 	user, err := h.service.AuthenticateSuperuser(c.Request.Context(), request.Email, request.Password)
 	if err != nil {
-		h.handleError(c, "login_error.html", "Invalid email or password", http.StatusUnauthorized)
+		// Failures for registered emails are recorded under the account, unknown emails under none
+		userID := uuid.Nil
+		var loginErr *services.LoginError
+		if errors.As(err, &loginErr) {
+			userID = loginErr.UserID
+		}
+		h.recordAudit(c, userID, services.AuditLoginFailed, map[string]interface{}{"email_hash": services.AuditEmailHash(request.Email), "reason": loginFailureReason(err)})
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		strategy := responses.GetResponseStrategy(c)
+		strategy.Respond(c, map[string]interface{}{
+			"template": "email_not_verified.html",
+			"error":    "Please verify your email address before logging in",
+			"email":    request.Email,
+		}, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		h.handleLoginError(c, "login_error.html", "Invalid email or password", err)
 		return
 	}
 
-	token, err := h.tokenManager.GenerateToken(user.Email, configs.TokenAccessDuration) // Use username/email
+	// Superusers with 2FA enabled only get a pending token until they pass the code challenge
+	if user.Is2FAEnabled {
+		mfaToken, _, err := h.tokenManager.GenerateToken(tokens.Claims{
+			UserID:   user.ID,
+			Username: user.Email,
+			Role:     user.Role,
+			Purpose:  tokens.PurposeMFAPending,
+			Duration: configs.TokenMFADuration,
+		})
+		if err != nil {
+			h.handleError(c, "login_error.html", "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		cookies.Set(c, configs.MFAPendingCookie, mfaToken, int(configs.TokenMFADuration.Seconds()))
+
+		strategy := responses.GetResponseStrategy(c)
+		strategy.Respond(c, map[string]interface{}{
+			"template":     "2fa_challenge.html",
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		}, http.StatusOK)
+		return
+	}
+
+	pair, err := h.issueAccessToken(c, user)
 	if err != nil {
 		h.handleError(c, "login_error.html", "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(c, user.ID, services.AuditLogin, map[string]interface{}{"method": "password", "session_id": pair.AccessPayload.SessionID.String()})
+	h.handleTokenSuccess(c, "login_success.html", "Login successful", pair)
+}
+
+// LoginVerify2FAHandler completes a login by exchanging a pending token and a valid 2FA code for an access token.
+func (h *SuperuserHandler) LoginVerify2FAHandler(c *gin.Context) {
+	var request struct {
+		Code     string `form:"code" binding:"required"`
+		MFAToken string `form:"mfa_token"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "2fa_verify.html", "Invalid 2FA code", http.StatusBadRequest)
+		return
+	}
+
+	// Browsers send the pending token as a cookie, API clients may post it instead
+	mfaToken := request.MFAToken
+	if cookie, ok := cookies.Get(c, configs.MFAPendingCookie); ok && mfaToken == "" {
+		mfaToken = cookie
+	}
+
+	payload, err := h.tokenManager.ValidateToken(mfaToken)
+	if err != nil || payload.Purpose != tokens.PurposeMFAPending {
+		h.handleError(c, "2fa_verify.html", "Login session expired, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.service.CompleteLogin2FA(c.Request.Context(), payload.UserID, request.Code)
+	if err != nil {
+		h.recordAudit(c, payload.UserID, services.AuditLoginFailed, map[string]interface{}{"reason": loginFailureReason(err), "method": "2fa"})
+		h.handleLoginError(c, "2fa_verify.html", "Invalid 2FA code", err)
+		return
+	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("SuperUserAuthorization", token, int(configs.TokenAccessDuration.Seconds()), "/", "", false, true)
-	h.handleSuccess(c, "login_success.html", "Login successful", http.StatusOK)
+	pair, err := h.issueAccessToken(c, user)
+	if err != nil {
+		h.handleError(c, "2fa_verify.html", "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	cookies.Clear(c, configs.MFAPendingCookie)
+	h.recordAudit(c, user.ID, services.AuditLogin, map[string]interface{}{"method": "2fa", "session_id": pair.AccessPayload.SessionID.String()})
+	h.handleTokenSuccess(c, "login_success.html", "Login successful", pair)
+}
+
+// handleLoginError responds to a failed login. Lockouts get their own message and a Retry-After header,
+// any other failure the given message, which must not reveal whether the email exists.
+func (h *SuperuserHandler) handleLoginError(c *gin.Context, template string, message string, err error) {
+	var locked *services.AccountLockedError
+	if !errors.As(err, &locked) {
+		h.handleError(c, template, message, http.StatusUnauthorized)
+		return
+	}
+
+	// Stored lockouts only keep whole seconds, all of them are reported that way
+	until := locked.Until.UTC().Truncate(time.Second)
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":     template,
+		"error":        "Too many failed login attempts. Account locked until " + until.Format("2006-01-02 15:04:05 MST"),
+		"locked_until": until,
+		"retry_after":  retryAfter,
+	}, http.StatusTooManyRequests)
+}
+
+// issueAccessToken starts a new session for the superuser and stores its tokens in the session cookies.
+func (h *SuperuserHandler) issueAccessToken(c *gin.Context, user *types.SuperUserType) (*tokens.TokenPair, error) {
+	pair, err := h.sessions.StartSession(c.Request.Context(), user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to record the login should not fail it
+	if err := h.service.RecordLogin(c.Request.Context(), user.ID, c.ClientIP()); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to record login", "superuser_id", user.ID, "error", err)
+	}
+
+	middlewares.SetSessionCookies(c, pair)
+	return pair, nil
+}
+
+// handleTokenSuccess responds like handleSuccess, and also puts the tokens in the body for JSON clients
+// so scripts and CLI tools can send them as a Bearer header without keeping a cookie jar.
+func (h *SuperuserHandler) handleTokenSuccess(c *gin.Context, template string, message string, pair *tokens.TokenPair) {
+	strategy := responses.GetResponseStrategy(c)
+	data := map[string]interface{}{
+		"template": template,
+		"message":  message,
+	}
+
+	if _, ok := strategy.(*responses.JSONResponseStrategy); ok {
+		data["access_token"] = pair.AccessToken
+		data["token_type"] = "Bearer"
+		data["expires_in"] = int(time.Until(pair.AccessPayload.ExpiredAt).Seconds())
+		// A replayed refresh within the grace window keeps the current refresh token
+		if pair.RefreshToken != "" {
+			data["refresh_token"] = pair.RefreshToken
+			data["refresh_expires_in"] = int(time.Until(pair.RefreshExpiresAt).Seconds())
+		}
+	}
+
+	strategy.Respond(c, data, http.StatusOK)
+}
+
+// TokenRefreshHandler rotates the refresh token and issues a new access token.
+func (h *SuperuserHandler) TokenRefreshHandler(c *gin.Context) {
+	// Browsers send the refresh token as a cookie, API clients may post it instead
+	refreshToken, ok := cookies.Get(c, configs.RefreshCookie)
+	if !ok {
+		refreshToken = c.PostForm("refresh_token")
+	}
+
+	pair, err := h.sessions.RefreshSession(c.Request.Context(), refreshToken)
+	if err != nil {
+		middlewares.ClearSessionCookies(c)
+		h.handleError(c, "token_refresh.html", "Session expired, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	middlewares.SetSessionCookies(c, pair)
+	h.handleTokenSuccess(c, "token_refresh.html", "Session refreshed", pair)
+}
+
+// JWKSHandler publishes the public keys that verify our tokens so other services can check them offline.
+// It is not wrapped in the standard response since JWKS clients expect the bare key set.
+func (h *SuperuserHandler) JWKSHandler(c *gin.Context) {
+	provider, ok := h.tokenManager.(tokens.KeySetProvider)
+	if !ok {
+		h.handleError(c, "error.html", "Tokens are not signed with public keys", http.StatusNotFound)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, provider.JWKS())
 }
 
 func (h *SuperuserHandler) LogoutSuperuserHandler(c *gin.Context) {
-	c.SetCookie("SuperUserAuthorization", "", -1, "/", "", false, true)
+	// Revoke the session server-side so a copied token cannot be used after logout
+	if payload, ok := c.Value("tokenPayload").(*tokens.Payload); ok {
+		if err := h.sessions.EndSession(c.Request.Context(), payload); err != nil {
+			h.handleError(c, "index.html", "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	middlewares.ClearSessionCookies(c)
+	h.recordAudit(c, currentUserID(c), services.AuditLogout, map[string]interface{}{"session_id": c.GetString("sessionID")})
 	h.handleSuccess(c, "index.html", "Logout successful", http.StatusOK)
 }
 
+// SessionsListHandler lists the active sessions of the superuser with their device, IP and last-seen time.
+func (h *SuperuserHandler) SessionsListHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "sessions_panel.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := h.sessions.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "sessions_panel.html", "Failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":           "sessions_panel.html",
+		"sessions":           sessions,
+		"current_session_id": c.GetString("sessionID"),
+	}, http.StatusOK)
+}
+
+// RevokeSessionHandler logs out one of the superuser's other sessions and re-renders the sessions panel.
+func (h *SuperuserHandler) RevokeSessionHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "sessions_panel.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.handleError(c, "sessions_panel.html", "Invalid session ID format", http.StatusBadRequest)
+		return
+	}
+
+	// The current session is ended through logout so its cookies are cleared too
+	if sessionID.String() == c.GetString("sessionID") {
+		h.handleError(c, "sessions_panel.html", "Use logout to end the current session", http.StatusBadRequest)
+		return
+	}
+
+	err = h.sessions.RevokeSession(c.Request.Context(), userID, sessionID)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		h.handleError(c, "sessions_panel.html", "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.handleError(c, "sessions_panel.html", "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	h.SessionsListHandler(c)
+}
+
+// RevokeOtherSessionsHandler logs the superuser out of every device except the current one.
+func (h *SuperuserHandler) RevokeOtherSessionsHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "sessions_revoked.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+	sessionID, err := uuid.Parse(c.GetString("sessionID"))
+	if err != nil {
+		h.handleError(c, "sessions_revoked.html", "Invalid session", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessions.RevokeOtherSessions(c.Request.Context(), userID, sessionID); err != nil {
+		h.handleError(c, "sessions_revoked.html", "Failed to log out other devices", http.StatusInternalServerError)
+		return
+	}
+
+	// Lets the sessions panel reload itself
+	c.Header("HX-Trigger", "sessionsChanged")
+	h.handleSuccess(c, "sessions_revoked.html", "All other devices have been logged out", http.StatusOK)
+}
+
 func (h *SuperuserHandler) DashboardSuperuserHandler(c *gin.Context) {
-	userID, _ := c.Get("userID") // Assuming user ID is in context from JWT middleware
+	userID := c.GetString("userID") // Set from the token subject by AuthTokenMiddleware
 
 	strategy := responses.GetResponseStrategy(c)
 	strategy.Respond(c, map[string]interface{}{
@@ -139,50 +472,118 @@ func (h *SuperuserHandler) DashboardSuperuserHandler(c *gin.Context) {
 	}, http.StatusOK)
 }
 
+func (h *SuperuserHandler) PasswordResetRequestRender(c *gin.Context) {
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template": "password_reset_request.html",
+		"title":    "Reset Password",
+	}, http.StatusOK)
+}
+
+func (h *SuperuserHandler) PasswordResetRender(c *gin.Context) {
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":            "password_reset_form.html",
+		"title":               "Choose a New Password",
+		"token":               c.Param("token"),
+		"password_min_length": configs.PasswordMinLength,
+	}, http.StatusOK)
+}
+
 func (h *SuperuserHandler) PasswordResetRequestHandler(c *gin.Context) {
 	var request struct {
 		Email string `form:"email" binding:"required,email"`
 	}
 
 	if err := c.ShouldBind(&request); err != nil {
-		h.handleError(c, "password_reset_request.html", "Invalid email address", http.StatusBadRequest)
+		h.handleError(c, "password_reset_error.html", "Invalid email address", http.StatusBadRequest)
 		return
 	}
 
 	err := h.service.SendPasswordResetEmail(c.Request.Context(), request.Email)
 	if err != nil {
-		h.handleError(c, "password_reset_request.html", "Failed to send reset email", http.StatusInternalServerError)
+		h.handleError(c, "password_reset_error.html", "Failed to send reset email", http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(c, uuid.Nil, services.AuditPasswordResetRequested, map[string]interface{}{"email_hash": services.AuditEmailHash(request.Email)})
 
-	h.handleSuccess(c, "password_reset_sent.html", "Password reset email sent successfully", http.StatusOK)
+	// Same message whether or not the email exists to avoid leaking registered addresses
+	h.handleSuccess(c, "password_reset_sent.html", "If an account exists for that email, a password reset link has been sent", http.StatusOK)
 }
 
 func (h *SuperuserHandler) PasswordResetHandler(c *gin.Context) {
 	var request struct {
-		Password string `form:"password" binding:"required,min=6"`
+		Password string `form:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBind(&request); err != nil {
-		h.handleError(c, "password_reset_form.html", "Invalid password", http.StatusBadRequest)
+		h.handleError(c, "password_reset_error.html", "Invalid password", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.ResetPassword(c.Request.Context(), c.Param("token"), request.Password)
+	userID, err := h.service.ResetPassword(c.Request.Context(), c.Param("token"), request.Password)
+	if h.handlePasswordError(c, "password_reset_error.html", err) {
+		return
+	}
 	if err != nil {
-		h.handleError(c, "password_reset_form.html", "Failed to reset password", http.StatusInternalServerError)
+		h.handleError(c, "password_reset_error.html", "Reset link is invalid or has expired", http.StatusBadRequest)
 		return
 	}
+	h.recordAudit(c, userID, services.AuditPasswordReset, nil)
 
 	h.handleSuccess(c, "password_reset_success.html", "Password reset successful", http.StatusOK)
 }
 
+// Enable2FAViewHandler shows the pending 2FA enrollment, if one was started, without changing it.
+func (h *SuperuserHandler) Enable2FAViewHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "2fa_enable.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.service.Pending2FA(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "2fa_enable.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.respondEnrollment(c, enrollment)
+}
+
+// Enable2FAHandler starts 2FA enrollment by generating a new TOTP secret and QR code.
+// It replaces any pending secret, so it is a POST and goes through the CSRF check.
 func (h *SuperuserHandler) Enable2FAHandler(c *gin.Context) {
-	strategy := responses.GetResponseStrategy(c)
-	strategy.Respond(c, map[string]interface{}{
+	// Retrieve the user ID from the context
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "2fa_enable.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.service.Setup2FA(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "2fa_enable.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.respondEnrollment(c, enrollment)
+}
+
+// respondEnrollment renders a 2FA enrollment, or the button starting one when enrollment is nil.
+func (h *SuperuserHandler) respondEnrollment(c *gin.Context, enrollment *twofactor.Enrollment) {
+	data := map[string]interface{}{
 		"template": "2fa_enable.html",
 		"title":    "Enable Two-Factor Authentication",
-	}, http.StatusOK)
+		"pending":  enrollment != nil,
+	}
+	if enrollment != nil {
+		data["secret"] = enrollment.Secret
+		data["otpauth_uri"] = enrollment.URI
+		// The QR code is embedded as a data URI so it renders without a second request
+		data["qr_code"] = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode))
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, data, http.StatusOK)
 }
 
 // Verify2FAHandler verifies the 2FA code for the authenticated superuser.
@@ -208,13 +609,84 @@ func (h *SuperuserHandler) Verify2FAHandler(c *gin.Context) {
 	}
 
 	// Call Verify2FA with the correct arguments
-	err = h.service.Verify2FA(c.Request.Context(), userID, request.Code)
+	recoveryCodes, err := h.service.Verify2FA(c.Request.Context(), userID, request.Code)
 	if err != nil {
-		h.handleError(c, "2fa_verify.html", "Failed to verify 2FA code", http.StatusUnauthorized)
+		h.handleLoginError(c, "2fa_verify.html", "Failed to verify 2FA code", err)
 		return
 	}
 
-	h.handleSuccess(c, "2fa_success.html", "2FA verified successfully", http.StatusOK)
+	// Recovery codes are only returned when 2FA was enabled by this verification
+	if len(recoveryCodes) > 0 {
+		h.recordAudit(c, userID, services.AuditTwoFactorEnabled, nil)
+	}
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":       "2fa_success.html",
+		"message":        "2FA verified successfully",
+		"recovery_codes": recoveryCodes,
+	}, http.StatusOK)
+}
+
+// RecoveryCodesViewHandler shows how many recovery codes are left and which have been used.
+func (h *SuperuserHandler) RecoveryCodesViewHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "recovery_codes.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.ListRecoveryCodes(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "recovery_codes.html", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	remaining := 0
+	for _, code := range codes {
+		if code.UsedAt == 0 {
+			remaining++
+		}
+	}
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":       "recovery_codes.html",
+		"title":          "Recovery Codes",
+		"recovery_codes": codes,
+		"remaining":      remaining,
+	}, http.StatusOK)
+}
+
+// RecoveryCodesRegenerateHandler replaces all recovery codes after the superuser re-enters their password.
+func (h *SuperuserHandler) RecoveryCodesRegenerateHandler(c *gin.Context) {
+	var request struct {
+		Password string `form:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		h.handleError(c, "recovery_codes_error.html", "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		h.handleError(c, "recovery_codes_error.html", "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), userID, request.Password)
+	if err != nil {
+		h.handleLoginError(c, "recovery_codes_error.html", "Failed to regenerate recovery codes", err)
+		return
+	}
+	h.recordAudit(c, userID, services.AuditRecoveryCodesRenewed, nil)
+
+	strategy := responses.GetResponseStrategy(c)
+	strategy.Respond(c, map[string]interface{}{
+		"template":       "2fa_success.html",
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": recoveryCodes,
+	}, http.StatusOK)
 }
 
 func (h *SuperuserHandler) TestTemplate(c *gin.Context) {
@@ -257,12 +729,22 @@ func (h *SuperuserHandler) ProfileUpdateHandler(c *gin.Context) {
 		return
 	}
 
+	// The current session stays logged in when the password changes
+	sessionID, _ := uuid.Parse(c.GetString("sessionID"))
+
 	// Call UpdateProfile with the correct arguments
-	err = h.service.UpdateProfile(c.Request.Context(), userID, request.Username, request.Password)
+	err = h.service.UpdateProfile(c.Request.Context(), userID, sessionID, request.Username, request.Password)
+	if h.handlePasswordError(c, "profile_edit.html", err) {
+		return
+	}
 	if err != nil {
 		h.handleError(c, "profile_edit.html", "Failed to update profile", http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(c, userID, services.AuditProfileUpdated, map[string]interface{}{"username": request.Username})
+	if request.Password != "" {
+		h.recordAudit(c, userID, services.AuditPasswordChanged, nil)
+	}
 
 	h.handleSuccess(c, "profile_success.html", "Profile updated successfully", http.StatusOK)
 }
@@ -288,6 +770,7 @@ func (h *SuperuserHandler) FileUploadHandler(c *gin.Context) {
 		}, http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(c, currentUserID(c), services.AuditFileUploaded, map[string]interface{}{"file": file.Filename, "size": file.Size})
 
 	strategy.Respond(c, map[string]interface{}{
 		"template": "file_upload_success.html",
@@ -298,8 +781,7 @@ func (h *SuperuserHandler) FileUploadHandler(c *gin.Context) {
 func (h *SuperuserHandler) FileDownloadHandler(c *gin.Context) {
 	strategy := responses.GetResponseStrategy(c)
 
-	fileID := c.Param("file_id")
-	filePath, err := h.service.GetFilePath(fileID)
+	filePath, err := h.service.GetFilePath(c.Param("filename"))
 	if err != nil {
 		strategy.Respond(c, map[string]interface{}{
 			"template": "file_download.html",
@@ -308,5 +790,6 @@ func (h *SuperuserHandler) FileDownloadHandler(c *gin.Context) {
 		return
 	}
 
+	h.recordAudit(c, currentUserID(c), services.AuditFileDownloaded, map[string]interface{}{"file": filePath})
 	c.File(filePath)
 }
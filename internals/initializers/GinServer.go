@@ -4,7 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,6 +14,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lordofthemind/htmx_GO/internals/configs"
 	"github.com/lordofthemind/htmx_GO/pkgs/helpers"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+	"github.com/lordofthemind/htmx_GO/pkgs/middlewares"
 )
 
 // ServerSetup defines the interface for setting up a Gin server
@@ -26,7 +28,10 @@ type CorsServerSetup struct{}
 
 // SetUpServer sets up a Gin server with CORS middleware
 func (c *CorsServerSetup) SetUpServer() (*gin.Engine, error) {
-	router := gin.Default()
+	router, err := newRouter()
+	if err != nil {
+		return nil, err
+	}
 
 	// Serve static files using the path from the config
 	router.Static("/static", configs.StaticPath)
@@ -47,8 +52,13 @@ func (c *CorsServerSetup) SetUpServer() (*gin.Engine, error) {
 	router.Use(cors.New(config))
 
 	// Log CORS settings for debugging
-	log.Printf("CORS configured with origins: %v, methods: %v, headers: %v, expose headers: %v, allow credentials: %v",
-		config.AllowOrigins, config.AllowMethods, config.AllowHeaders, config.ExposeHeaders, config.AllowCredentials)
+	slog.Debug("CORS configured",
+		"origins", config.AllowOrigins,
+		"methods", config.AllowMethods,
+		"headers", config.AllowHeaders,
+		"expose_headers", config.ExposeHeaders,
+		"allow_credentials", config.AllowCredentials,
+	)
 
 	return router, nil
 }
@@ -58,7 +68,10 @@ type BasicServerSetup struct{}
 
 // SetUpServer sets up a basic Gin server without CORS
 func (b *BasicServerSetup) SetUpServer() (*gin.Engine, error) {
-	router := gin.Default()
+	router, err := newRouter()
+	if err != nil {
+		return nil, err
+	}
 
 	// Serve static files using the path from the config
 	router.Static("/static", configs.StaticPath)
@@ -69,16 +82,29 @@ func (b *BasicServerSetup) SetUpServer() (*gin.Engine, error) {
 	return router, nil
 }
 
+// newRouter creates the Gin engine both setups share.
+func newRouter() (*gin.Engine, error) {
+	// Requests and panics are logged through slog by the middlewares instead of gin's own logger
+	router := gin.New()
+	router.Use(middlewares.RecoveryMiddleware())
+
+	// Only proxies listed in the config may set the client IP through X-Forwarded-For
+	if err := router.SetTrustedProxies(configs.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid server.trusted_proxies: %w", err)
+	}
+	return router, nil
+}
+
 // SetUpServerWithOptionalCORS sets up the Gin router with or without CORS based on the UseCORS flag.
 func SetUpServerWithOptionalCORS() (*gin.Engine, error) {
 	var serverSetup ServerSetup
 
 	// Choose the server setup based on UseCORS config
 	if configs.UseCORS {
-		fmt.Println("Setting up server with CORS...")
+		slog.Info("Setting up server", "cors", true)
 		serverSetup = &CorsServerSetup{}
 	} else {
-		fmt.Println("Setting up server without CORS...")
+		slog.Info("Setting up server", "cors", false)
 		serverSetup = &BasicServerSetup{}
 	}
 
@@ -107,21 +133,21 @@ func StartGinServer(router *gin.Engine) error {
 		server.TLSConfig = &tls.Config{
 			Certificates: []tls.Certificate{cert},
 		}
-		log.Printf("Gin server is running on port %d with TLS", configs.Port)
+		slog.Info("Gin server is running", "port", configs.Port, "tls", true)
 
 		// Start the server with TLS
 		go func() {
 			if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-				log.Printf("ListenAndServeTLS error: %v", err)
+				slog.Error("ListenAndServeTLS failed", "error", err)
 			}
 		}()
 	} else {
-		log.Printf("Gin server is running on port %d without TLS", configs.Port)
+		slog.Info("Gin server is running", "port", configs.Port, "tls", false)
 
 		// Start the server without TLS
 		go func() {
 			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Printf("ListenAndServe error: %v", err)
+				slog.Error("ListenAndServe failed", "error", err)
 			}
 		}()
 	}
@@ -135,7 +161,7 @@ func GracefulShutdown(server *http.Server) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Context with timeout for shutdown
 	ctxShutDown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -143,18 +169,18 @@ func GracefulShutdown(server *http.Server) {
 
 	// Attempt to shut down the server gracefully
 	if err := server.Shutdown(ctxShutDown); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Warn("Server forced to shutdown", "error", err)
 
 		// Retry mechanism for shutdown
 		for retries := 0; retries < 3; retries++ {
-			log.Printf("Retrying shutdown... attempt %d", retries+1)
+			slog.Info("Retrying shutdown", "attempt", retries+1)
 			if err := server.Shutdown(ctxShutDown); err == nil {
-				log.Println("Server shutdown successfully on retry")
+				slog.Info("Server shutdown successfully on retry")
 				return
 			}
 		}
-		log.Fatalf("Failed to shutdown server gracefully after retries: %v", err)
+		logging.Fatal("Failed to shutdown server gracefully after retries", "error", err)
 	}
 
-	log.Println("Server shutdown successfully")
+	slog.Info("Server shutdown successfully")
 }
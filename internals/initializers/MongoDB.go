@@ -4,7 +4,7 @@ package initializers
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -37,12 +37,12 @@ func ConnectToMongoDB(ctx context.Context, dsn string, timeout time.Duration, ma
 			client, err = mongo.Connect(ctx, options.Client().ApplyURI(dsn))
 			if err == nil {
 				// Successfully connected, return the client
-				log.Println("Connected to MongoDB successfully")
+				slog.Info("Connected to MongoDB")
 				return client, nil
 			}
 
 			// Log the failure and retry after a delay
-			log.Printf("Connection attempt %d failed: %v\n", i+1, err)
+			slog.Warn("MongoDB connection attempt failed", "attempt", i+1, "error", err)
 			time.Sleep(retryDelay) // Wait before the next retry
 		}
 	}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *types.APIKeyType) error
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKeyType, error)
+	ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]*types.APIKeyType, error)
+	CountUserAPIKeys(ctx context.Context, userID uuid.UUID) (int64, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) error
+}
+
+type MongoAPIKeyRepo struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAPIKeyRepository(db *mongo.Database) APIKeyRepository {
+	return &MongoAPIKeyRepo{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+// CreateAPIKeyIndexes adds the unique prefix index used to look keys up and the index used to list them.
+func CreateAPIKeyIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("api_keys").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+// CreateAPIKey stores a new API key.
+func (r *MongoAPIKeyRepo) CreateAPIKey(ctx context.Context, key *types.APIKeyType) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// FindAPIKeyByPrefix finds an API key by its public prefix.
+func (r *MongoAPIKeyRepo) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKeyType, error) {
+	var key types.APIKeyType
+	err := r.collection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("api key not found")
+	}
+	return &key, err
+}
+
+// ListUserAPIKeys lists the active API keys of a superuser, newest first.
+func (r *MongoAPIKeyRepo) ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]*types.APIKeyType, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "revoked": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*types.APIKeyType
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CountUserAPIKeys counts the active API keys of a superuser.
+func (r *MongoAPIKeyRepo) CountUserAPIKeys(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "revoked": false})
+}
+
+// RevokeAPIKey revokes an API key of a superuser and reports whether an active key was found.
+func (r *MongoAPIKeyRepo) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	filter := bson.M{"_id": id, "user_id": userID, "revoked": false}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RecordAPIKeyUsage counts a request made with an API key and records when and where it came from.
+func (r *MongoAPIKeyRepo) RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"last_used_at": usedAt, "last_used_ip": ipAddress},
+		"$inc": bson.M{"request_count": 1},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAuditSequenceTaken is returned when another entry already holds the sequence of a new entry,
+// which happens when several instances append to the chain at the same time.
+var ErrAuditSequenceTaken = errors.New("audit log sequence already taken")
+
+// ErrAuditCheckpointTaken is returned when another checkpoint already holds the number of a new one.
+var ErrAuditCheckpointTaken = errors.New("audit checkpoint number already taken")
+
+type AuditLogRepository interface {
+	CreateAuditLog(ctx context.Context, entry *types.UserActivityLog) error
+	ListAuditLogs(ctx context.Context, filter types.UserActivityLogFilter, limit, skip int64) ([]*types.UserActivityLog, error)
+	LatestAuditLog(ctx context.Context) (*types.UserActivityLog, error)
+	ListAuditLogsAfter(ctx context.Context, sequence, limit int64) ([]*types.UserActivityLog, error)
+	CreateAuditCheckpoint(ctx context.Context, checkpoint *types.AuditCheckpoint) error
+	LatestAuditCheckpoint(ctx context.Context) (*types.AuditCheckpoint, error)
+	ListAuditCheckpoints(ctx context.Context) ([]*types.AuditCheckpoint, error)
+}
+
+type MongoAuditLogRepo struct {
+	collection  *mongo.Collection
+	checkpoints *mongo.Collection
+}
+
+func NewMongoAuditLogRepository(db *mongo.Database) AuditLogRepository {
+	return &MongoAuditLogRepo{
+		collection:  db.Collection("audit_logs"),
+		checkpoints: db.Collection("audit_checkpoints"),
+	}
+}
+
+// CreateAuditLogIndexes adds the retention TTL index, the indexes used by the audit viewer and the activity timeline
+// and the unique chain sequence and checkpoint number. Entries recorded before hash chaining have no sequence and are left out of it.
+func CreateAuditLogIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("audit_logs").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "metadata.target_ids", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"sequence": bson.M{"$gt": 0}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("audit_checkpoints").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateAuditLog stores a new audit log entry.
+func (r *MongoAuditLogRepo) CreateAuditLog(ctx context.Context, entry *types.UserActivityLog) error {
+	_, err := r.collection.InsertOne(ctx, entry)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAuditSequenceTaken
+	}
+	return err
+}
+
+// LatestAuditLog returns the entry at the head of the hash chain, or nil when the chain is empty.
+func (r *MongoAuditLogRepo) LatestAuditLog(ctx context.Context) (*types.UserActivityLog, error) {
+	var entry types.UserActivityLog
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"sequence": bson.M{"$gt": 0}}, opts).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListAuditLogsAfter returns up to limit chained entries following the given sequence, in chain order.
+func (r *MongoAuditLogRepo) ListAuditLogsAfter(ctx context.Context, sequence, limit int64) ([]*types.UserActivityLog, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"sequence": bson.M{"$gt": sequence}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]*types.UserActivityLog, 0)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CreateAuditCheckpoint stores a signed checkpoint of the hash chain.
+func (r *MongoAuditLogRepo) CreateAuditCheckpoint(ctx context.Context, checkpoint *types.AuditCheckpoint) error {
+	_, err := r.checkpoints.InsertOne(ctx, checkpoint)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAuditCheckpointTaken
+	}
+	return err
+}
+
+// LatestAuditCheckpoint returns the checkpoint with the highest number, or nil when there is none.
+func (r *MongoAuditLogRepo) LatestAuditCheckpoint(ctx context.Context) (*types.AuditCheckpoint, error) {
+	var checkpoint types.AuditCheckpoint
+	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
+	err := r.checkpoints.FindOne(ctx, bson.M{}, opts).Decode(&checkpoint)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// ListAuditCheckpoints returns every checkpoint, lowest number first.
+func (r *MongoAuditLogRepo) ListAuditCheckpoints(ctx context.Context) ([]*types.AuditCheckpoint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: 1}})
+	cursor, err := r.checkpoints.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	checkpoints := make([]*types.AuditCheckpoint, 0)
+	if err := cursor.All(ctx, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// ListAuditLogs returns the entries matching filter, newest first.
+func (r *MongoAuditLogRepo) ListAuditLogs(ctx context.Context, filter types.UserActivityLogFilter, limit, skip int64) ([]*types.UserActivityLog, error) {
+	query := bson.M{}
+	if filter.UserID != uuid.Nil {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Subject != uuid.Nil {
+		query["$or"] = bson.A{bson.M{"user_id": filter.Subject}, bson.M{"metadata.target_ids": filter.Subject.String()}}
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.IPAddress != "" {
+		query["ip_address"] = filter.IPAddress
+	}
+	if filter.UserAgent != "" {
+		query["user_agent"] = filter.UserAgent
+	}
+	timestamp := bson.M{}
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timestamp["$lt"] = filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(limit).SetSkip(skip)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]*types.UserActivityLog, 0)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+type inMemoryAPIKeyRepo struct {
+	data map[uuid.UUID]*types.APIKeyType
+	mu   sync.RWMutex
+}
+
+// NewInMemoryAPIKeyRepository initializes an in-memory API key repository.
+func NewInMemoryAPIKeyRepository() APIKeyRepository {
+	return &inMemoryAPIKeyRepo{
+		data: make(map[uuid.UUID]*types.APIKeyType),
+	}
+}
+
+// CreateAPIKey stores a new API key in memory.
+func (r *inMemoryAPIKeyRepo) CreateAPIKey(ctx context.Context, key *types.APIKeyType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.data {
+		if existing.Prefix == key.Prefix {
+			return errors.New("api key prefix already exists")
+		}
+	}
+	stored := *key
+	stored.Scopes = append([]string(nil), key.Scopes...)
+	r.data[key.ID] = &stored
+	return nil
+}
+
+// FindAPIKeyByPrefix finds an API key by its public prefix in memory.
+func (r *inMemoryAPIKeyRepo) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKeyType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.data {
+		if key.Prefix == prefix {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, errors.New("api key not found")
+}
+
+// ListUserAPIKeys lists the active API keys of a superuser in memory, newest first.
+func (r *inMemoryAPIKeyRepo) ListUserAPIKeys(ctx context.Context, userID uuid.UUID) ([]*types.APIKeyType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*types.APIKeyType
+	for _, key := range r.data {
+		if key.UserID == userID && !key.Revoked {
+			found := *key
+			keys = append(keys, &found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// CountUserAPIKeys counts the active API keys of a superuser in memory.
+func (r *inMemoryAPIKeyRepo) CountUserAPIKeys(ctx context.Context, userID uuid.UUID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, key := range r.data {
+		if key.UserID == userID && !key.Revoked {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RevokeAPIKey revokes an API key of a superuser in memory and reports whether an active key was found.
+func (r *inMemoryAPIKeyRepo) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.data[id]
+	if !ok || key.UserID != userID || key.Revoked {
+		return false, nil
+	}
+	key.Revoked = true
+	return true, nil
+}
+
+// RecordAPIKeyUsage counts a request made with an API key in memory.
+func (r *inMemoryAPIKeyRepo) RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.data[id]; ok {
+		key.LastUsedAt = usedAt
+		key.LastUsedIP = ipAddress
+		key.RequestCount++
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+type inMemoryAuditLogRepo struct {
+	entries     []*types.UserActivityLog // oldest first
+	checkpoints []*types.AuditCheckpoint // oldest first
+	mu          sync.RWMutex
+}
+
+// NewInMemoryAuditLogRepository initializes an in-memory audit log repository.
+// Entries past their retention are swept every sweepInterval until ctx is done.
+func NewInMemoryAuditLogRepository(ctx context.Context, sweepInterval time.Duration) AuditLogRepository {
+	r := &inMemoryAuditLogRepo{}
+
+	if sweepInterval > 0 {
+		go r.sweep(ctx, sweepInterval)
+	}
+	return r
+}
+
+// sweep periodically removes expired entries, mirroring the TTL index of the MongoDB repository.
+func (r *inMemoryAuditLogRepo) sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.removeExpired(now)
+		}
+	}
+}
+
+// removeExpired deletes entries that expired before now.
+func (r *inMemoryAuditLogRepo) removeExpired(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.entries[:0]
+	for _, entry := range r.entries {
+		if entry.ExpiresAt.IsZero() || now.Before(entry.ExpiresAt) {
+			kept = append(kept, entry)
+		}
+	}
+	for i := len(kept); i < len(r.entries); i++ {
+		r.entries[i] = nil
+	}
+	r.entries = kept
+}
+
+// CreateAuditLog stores a new audit log entry in memory.
+func (r *inMemoryAuditLogRepo) CreateAuditLog(ctx context.Context, entry *types.UserActivityLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.Sequence > 0 {
+		for _, existing := range r.entries {
+			if existing.Sequence == entry.Sequence {
+				return ErrAuditSequenceTaken
+			}
+		}
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+// LatestAuditLog returns the entry at the head of the hash chain in memory, or nil when the chain is empty.
+func (r *inMemoryAuditLogRepo) LatestAuditLog(ctx context.Context) (*types.UserActivityLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *types.UserActivityLog
+	for _, entry := range r.entries {
+		if entry.Sequence > 0 && (latest == nil || entry.Sequence > latest.Sequence) {
+			latest = entry
+		}
+	}
+	return latest, nil
+}
+
+// ListAuditLogsAfter returns up to limit chained entries following the given sequence in memory, in chain order.
+func (r *inMemoryAuditLogRepo) ListAuditLogsAfter(ctx context.Context, sequence, limit int64) ([]*types.UserActivityLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]*types.UserActivityLog, 0)
+	for _, entry := range r.entries {
+		if entry.Sequence > sequence {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	if int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// CreateAuditCheckpoint stores a signed checkpoint of the hash chain in memory.
+func (r *inMemoryAuditLogRepo) CreateAuditCheckpoint(ctx context.Context, checkpoint *types.AuditCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.checkpoints {
+		if stored.Number == checkpoint.Number {
+			return ErrAuditCheckpointTaken
+		}
+	}
+	r.checkpoints = append(r.checkpoints, checkpoint)
+	return nil
+}
+
+// LatestAuditCheckpoint returns the checkpoint with the highest number in memory, or nil when there is none.
+func (r *inMemoryAuditLogRepo) LatestAuditCheckpoint(ctx context.Context) (*types.AuditCheckpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *types.AuditCheckpoint
+	for _, checkpoint := range r.checkpoints {
+		if latest == nil || checkpoint.Number > latest.Number {
+			latest = checkpoint
+		}
+	}
+	return latest, nil
+}
+
+// ListAuditCheckpoints returns every checkpoint in memory, lowest number first.
+func (r *inMemoryAuditLogRepo) ListAuditCheckpoints(ctx context.Context) ([]*types.AuditCheckpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	checkpoints := append([]*types.AuditCheckpoint(nil), r.checkpoints...)
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Number < checkpoints[j].Number })
+	return checkpoints, nil
+}
+
+// ListAuditLogs returns the entries matching filter in memory, newest first.
+func (r *inMemoryAuditLogRepo) ListAuditLogs(ctx context.Context, filter types.UserActivityLogFilter, limit, skip int64) ([]*types.UserActivityLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]*types.UserActivityLog, 0)
+	for i := len(r.entries) - 1; i >= 0 && int64(len(entries)) < limit; i-- {
+		entry := r.entries[i]
+		if !matchesAuditFilter(entry, filter) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func matchesAuditFilter(entry *types.UserActivityLog, filter types.UserActivityLogFilter) bool {
+	if filter.UserID != uuid.Nil && entry.UserID != filter.UserID {
+		return false
+	}
+	if filter.Subject != uuid.Nil && entry.UserID != filter.Subject && !namesTarget(entry, filter.Subject) {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+	if filter.IPAddress != "" && entry.IPAddress != filter.IPAddress {
+		return false
+	}
+	if filter.UserAgent != "" && entry.UserAgent != filter.UserAgent {
+		return false
+	}
+	if !filter.From.IsZero() && entry.Timestamp.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !entry.Timestamp.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// namesTarget reports whether an entry lists userID in its target_ids metadata.
+func namesTarget(entry *types.UserActivityLog, userID uuid.UUID) bool {
+	switch targets := entry.Metadata["target_ids"].(type) {
+	case []string:
+		for _, target := range targets {
+			if target == userID.String() {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, target := range targets {
+			if target == userID.String() {
+				return true
+			}
+		}
+	}
+	return false
+}
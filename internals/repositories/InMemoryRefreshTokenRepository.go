@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+type inMemoryRefreshTokenRepo struct {
+	data map[uuid.UUID]*types.RefreshTokenType
+	mu   sync.RWMutex
+}
+
+// NewInMemoryRefreshTokenRepository initializes an in-memory refresh token repository.
+func NewInMemoryRefreshTokenRepository() RefreshTokenRepository {
+	return &inMemoryRefreshTokenRepo{
+		data: make(map[uuid.UUID]*types.RefreshTokenType),
+	}
+}
+
+// CreateRefreshToken stores a new refresh token in memory.
+func (r *inMemoryRefreshTokenRepo) CreateRefreshToken(ctx context.Context, token *types.RefreshTokenType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	stored := *token
+	r.data[token.ID] = &stored
+	return nil
+}
+
+// FindRefreshTokenByHash finds a refresh token by the hash of its value in memory.
+func (r *inMemoryRefreshTokenRepo) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*types.RefreshTokenType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.data {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, errors.New("refresh token not found")
+}
+
+// MarkRefreshTokenUsed marks an unused, unrevoked refresh token as used in memory.
+func (r *inMemoryRefreshTokenRepo) MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID, usedAt int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.data[id]
+	if !ok || token.UsedAt != 0 || token.Revoked {
+		return false, nil
+	}
+	token.UsedAt = usedAt
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of a family in memory.
+func (r *inMemoryRefreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.data {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+		}
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a superuser in memory.
+func (r *inMemoryRefreshTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.data {
+		if token.UserID == userID {
+			token.Revoked = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+type inMemorySessionRepo struct {
+	sessions      map[uuid.UUID]*types.SessionType
+	revokedTokens map[uuid.UUID]time.Time
+	mu            sync.RWMutex
+}
+
+// NewInMemorySessionRepository initializes an in-memory session repository.
+// Expired sessions and denylist entries are swept every sweepInterval until ctx is done.
+func NewInMemorySessionRepository(ctx context.Context, sweepInterval time.Duration) SessionRepository {
+	r := &inMemorySessionRepo{
+		sessions:      make(map[uuid.UUID]*types.SessionType),
+		revokedTokens: make(map[uuid.UUID]time.Time),
+	}
+
+	if sweepInterval > 0 {
+		go r.sweep(ctx, sweepInterval)
+	}
+	return r
+}
+
+// sweep periodically removes expired entries, mirroring the TTL indexes of the MongoDB repository.
+func (r *inMemorySessionRepo) sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.removeExpired(now)
+		}
+	}
+}
+
+// removeExpired deletes sessions and denylist entries that expired before now.
+func (r *inMemorySessionRepo) removeExpired(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(r.sessions, id)
+		}
+	}
+	for id, expiresAt := range r.revokedTokens {
+		if now.After(expiresAt) {
+			delete(r.revokedTokens, id)
+		}
+	}
+}
+
+// CreateSession stores a new session in memory.
+func (r *inMemorySessionRepo) CreateSession(ctx context.Context, session *types.SessionType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+// FindSessionByID finds a session by ID in memory.
+func (r *inMemorySessionRepo) FindSessionByID(ctx context.Context, id uuid.UUID) (*types.SessionType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	found := *session
+	return &found, nil
+}
+
+// ListUserSessions lists the active sessions of a superuser in memory, most recently used first.
+func (r *inMemorySessionRepo) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]*types.SessionType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []*types.SessionType
+	for _, session := range r.sessions {
+		if session.UserID == userID && !session.Revoked && session.ExpiresAt.After(now) {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// TouchSession records the access token issued by a refresh and extends the session expiry in memory.
+func (r *inMemorySessionRepo) TouchSession(ctx context.Context, id, tokenID uuid.UUID, lastSeenAt, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.TokenID = tokenID
+		session.LastSeenAt = lastSeenAt
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+// UpdateSessionActivity records the client and token of the latest authenticated request of a session in memory.
+func (r *inMemorySessionRepo) UpdateSessionActivity(ctx context.Context, id, tokenID uuid.UUID, userAgent, ipAddress string, lastSeenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.TokenID = tokenID
+		session.UserAgent = userAgent
+		session.IPAddress = ipAddress
+		session.LastSeenAt = lastSeenAt
+	}
+	return nil
+}
+
+// RevokeSession revokes a single session in memory.
+func (r *inMemorySessionRepo) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.Revoked = true
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every session of a superuser except exceptID in memory.
+func (r *inMemorySessionRepo) RevokeUserSessions(ctx context.Context, userID, exceptID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID && id != exceptID {
+			session.Revoked = true
+		}
+	}
+	return nil
+}
+
+// DenyToken adds a token ID to the denylist in memory.
+func (r *inMemorySessionRepo) DenyToken(ctx context.Context, tokenID uuid.UUID, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokedTokens[tokenID] = expiresAt
+	return nil
+}
+
+// IsTokenDenied reports whether a token ID is on the denylist in memory.
+func (r *inMemorySessionRepo) IsTokenDenied(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.revokedTokens[tokenID]
+	return ok, nil
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -127,27 +129,41 @@ func (r *inMemorySuperuserRepo) ListSuperusers(ctx context.Context, limit, skip
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var superusers []*types.SuperUserType
-	count := int64(0)
+	// Map iteration order is random, sort so pages are stable
+	all := r.sortedSuperusers()
+	if skip >= int64(len(all)) {
+		return nil, nil
+	}
+	end := skip + limit
+	if end > int64(len(all)) {
+		end = int64(len(all))
+	}
+	return all[skip:end], nil
+}
+
+// sortedSuperusers returns all superusers ordered by creation time. Callers must hold the lock.
+func (r *inMemorySuperuserRepo) sortedSuperusers() []*types.SuperUserType {
+	superusers := make([]*types.SuperUserType, 0, len(r.data))
 	for _, su := range r.data {
-		if count >= skip {
-			superusers = append(superusers, su)
-		}
-		if int64(len(superusers)) == limit {
-			break
-		}
-		count++
+		superusers = append(superusers, su)
 	}
-	return superusers, nil
+	sort.Slice(superusers, func(i, j int) bool {
+		if superusers[i].CreatedAt != superusers[j].CreatedAt {
+			return superusers[i].CreatedAt < superusers[j].CreatedAt
+		}
+		return superusers[i].ID.String() < superusers[j].ID.String()
+	})
+	return superusers
 }
 
-// UpdateResetToken updates the reset token for a superuser in memory.
-func (r *inMemorySuperuserRepo) UpdateResetToken(ctx context.Context, id uuid.UUID, token string) error {
+// UpdateResetToken updates the reset token and its expiry for a superuser in memory.
+func (r *inMemorySuperuserRepo) UpdateResetToken(ctx context.Context, id uuid.UUID, token string, expiresAt int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if su, ok := r.data[id]; ok {
 		su.ResetToken = token
+		su.ResetTokenExpiry = expiresAt
 		su.UpdatedAt = time.Now().Unix()
 		r.data[id] = su
 		return nil
@@ -155,6 +171,22 @@ func (r *inMemorySuperuserRepo) UpdateResetToken(ctx context.Context, id uuid.UU
 	return errors.New("superuser not found")
 }
 
+// ConsumeResetToken finds the superuser owning an unexpired reset token and clears the token in memory.
+func (r *inMemorySuperuserRepo) ConsumeResetToken(ctx context.Context, token string) (*types.SuperUserType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, su := range r.data {
+		if token != "" && su.ResetToken == token && su.ResetTokenExpiry > time.Now().Unix() {
+			su.ResetToken = ""
+			su.ResetTokenExpiry = 0
+			su.UpdatedAt = time.Now().Unix()
+			return su, nil
+		}
+	}
+	return nil, errors.New("superuser not found")
+}
+
 // Enable2FA enables or disables 2FA for a superuser in memory.
 func (r *inMemorySuperuserRepo) Enable2FA(ctx context.Context, id uuid.UUID, isEnabled bool) error {
 	r.mu.Lock()
@@ -175,6 +207,7 @@ func (r *inMemorySuperuserRepo) SoftDeleteSuperuser(ctx context.Context, id uuid
 	defer r.mu.Unlock()
 
 	if su, ok := r.data[id]; ok {
+		su.Archived = true
 		su.UpdatedAt = time.Now().Unix()
 		r.data[id] = su
 		return nil
@@ -182,13 +215,87 @@ func (r *inMemorySuperuserRepo) SoftDeleteSuperuser(ctx context.Context, id uuid
 	return errors.New("superuser not found")
 }
 
+// RestoreSuperuser clears the archived flag set by SoftDeleteSuperuser in memory.
+func (r *inMemorySuperuserRepo) RestoreSuperuser(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.Archived = false
+		su.UpdatedAt = time.Now().Unix()
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// RecordFailedLogin counts a failed login in memory and returns the number of failures since the last success or lockout.
+func (r *inMemorySuperuserRepo) RecordFailedLogin(ctx context.Context, id uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.FailedAttempts++
+		r.data[id] = su
+		return su.FailedAttempts, nil
+	}
+	return 0, errors.New("superuser not found")
+}
+
+// LockSuperuser refuses logins until lockedUntil in memory and starts counting failures again.
+func (r *inMemorySuperuserRepo) LockSuperuser(ctx context.Context, id uuid.UUID, lockedUntil int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.LockedUntil = lockedUntil
+		su.FailedAttempts = 0
+		su.LockoutCount++
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// UnlockSuperuser lifts a lockout in memory and clears the failure counters.
+func (r *inMemorySuperuserRepo) UnlockSuperuser(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.LockedUntil = 0
+		su.FailedAttempts = 0
+		su.LockoutCount = 0
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// RecordLogin stores the time and IP of a successful login in memory and clears the failure counters.
+func (r *inMemorySuperuserRepo) RecordLogin(ctx context.Context, id uuid.UUID, loginAt int64, ipAddress string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.LastLoginAt = loginAt
+		su.LastLoginIP = ipAddress
+		su.LockedUntil = 0
+		su.FailedAttempts = 0
+		su.LockoutCount = 0
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
 // SearchSuperusers allows partial search by full_name, username, or email in memory.
 func (r *inMemorySuperuserRepo) SearchSuperusers(ctx context.Context, searchQuery string) ([]*types.SuperUserType, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var results []*types.SuperUserType
-	for _, su := range r.data {
+	for _, su := range r.sortedSuperusers() {
 		if matchString(su.FullName, searchQuery) || matchString(su.Username, searchQuery) || matchString(su.Email, searchQuery) {
 			results = append(results, su)
 		}
@@ -203,7 +310,7 @@ func matchString(field, query string) bool {
 
 // containsIgnoreCase checks if a string contains another string, ignoring case.
 func containsIgnoreCase(a, b string) bool {
-	return len(b) > 0 && len(a) >= len(b) && (a == b || a[len(a)-len(b):] == b)
+	return strings.Contains(strings.ToLower(a), strings.ToLower(b))
 }
 
 // FindAll2FAEnabledSuperusers finds all superusers with 2FA enabled in memory.
@@ -251,6 +358,8 @@ func (r *inMemorySuperuserRepo) BulkUpdateSuperusers(ctx context.Context, ids []
 					su.Role = value.(string)
 				case "is_2fa_enabled":
 					su.Is2FAEnabled = value.(bool)
+				case "archived":
+					su.Archived = value.(bool)
 				}
 			}
 			su.UpdatedAt = time.Now().Unix()
@@ -259,3 +368,97 @@ func (r *inMemorySuperuserRepo) BulkUpdateSuperusers(ctx context.Context, ids []
 	}
 	return nil
 }
+
+// UpdateTOTPSecret stores a new TOTP secret for a superuser and resets the replay counter in memory.
+func (r *inMemorySuperuserRepo) UpdateTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.TOTPSecret = secret
+		su.TOTPLastUsedStep = 0
+		su.UpdatedAt = time.Now().Unix()
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// UpdateTOTPLastUsedStep records the last accepted TOTP time step in memory.
+func (r *inMemorySuperuserRepo) UpdateTOTPLastUsedStep(ctx context.Context, id uuid.UUID, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	su, ok := r.data[id]
+	if !ok {
+		return errors.New("superuser not found")
+	}
+	if su.TOTPLastUsedStep >= step {
+		return errors.New("2FA code has already been used")
+	}
+	su.TOTPLastUsedStep = step
+	su.UpdatedAt = time.Now().Unix()
+	r.data[id] = su
+	return nil
+}
+
+// UpdateRecoveryCodes replaces the stored recovery codes of a superuser in memory.
+func (r *inMemorySuperuserRepo) UpdateRecoveryCodes(ctx context.Context, id uuid.UUID, codes []types.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.RecoveryCodes = codes
+		su.UpdatedAt = time.Now().Unix()
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used in memory.
+func (r *inMemorySuperuserRepo) ConsumeRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	su, ok := r.data[id]
+	if !ok {
+		return errors.New("superuser not found")
+	}
+	for i := range su.RecoveryCodes {
+		if su.RecoveryCodes[i].Hash == hash && su.RecoveryCodes[i].UsedAt == 0 {
+			su.RecoveryCodes[i].UsedAt = time.Now().Unix()
+			su.UpdatedAt = time.Now().Unix()
+			return nil
+		}
+	}
+	return errors.New("invalid recovery code")
+}
+
+// MarkEmailVerified records that a superuser confirmed their email address in memory.
+func (r *inMemorySuperuserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.EmailVerified = true
+		su.UpdatedAt = time.Now().Unix()
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
+
+// UpdateVerifySentAt records when the last verification email was sent to a superuser in memory.
+func (r *inMemorySuperuserRepo) UpdateVerifySentAt(ctx context.Context, id uuid.UUID, sentAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if su, ok := r.data[id]; ok {
+		su.VerifySentAt = sentAt
+		su.UpdatedAt = time.Now().Unix()
+		r.data[id] = su
+		return nil
+	}
+	return errors.New("superuser not found")
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *types.RefreshTokenType) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*types.RefreshTokenType, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID, usedAt int64) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type MongoRefreshTokenRepo struct {
+	db *mongo.Collection
+}
+
+func NewMongoRefreshTokenRepository(db *mongo.Database) RefreshTokenRepository {
+	return &MongoRefreshTokenRepo{
+		db: db.Collection("refresh_tokens"),
+	}
+}
+
+// CreateRefreshTokenIndexes adds the TTL index that lets MongoDB remove expired refresh tokens,
+// the unique index refresh tokens are looked up by and the family index used to revoke a whole login.
+func CreateRefreshTokenIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+// CreateRefreshToken stores a new refresh token.
+func (r *MongoRefreshTokenRepo) CreateRefreshToken(ctx context.Context, token *types.RefreshTokenType) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	_, err := r.db.InsertOne(ctx, token)
+	return err
+}
+
+// FindRefreshTokenByHash finds a refresh token by the hash of its value.
+func (r *MongoRefreshTokenRepo) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*types.RefreshTokenType, error) {
+	var token types.RefreshTokenType
+	err := r.db.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("refresh token not found")
+	}
+	return &token, err
+}
+
+// MarkRefreshTokenUsed marks an unused, unrevoked refresh token as used in one step.
+// It reports false when the token was already used or revoked, which means it is being replayed.
+func (r *MongoRefreshTokenRepo) MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID, usedAt int64) (bool, error) {
+	filter := bson.M{"_id": id, "used_at": 0, "revoked": false}
+	result, err := r.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used_at": usedAt}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token rotated from the same login.
+func (r *MongoRefreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := r.db.UpdateMany(ctx, bson.M{"family_id": familyID}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every refresh token issued to a superuser.
+func (r *MongoRefreshTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
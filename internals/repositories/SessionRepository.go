@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *types.SessionType) error
+	FindSessionByID(ctx context.Context, id uuid.UUID) (*types.SessionType, error)
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]*types.SessionType, error)
+	TouchSession(ctx context.Context, id, tokenID uuid.UUID, lastSeenAt, expiresAt time.Time) error
+	UpdateSessionActivity(ctx context.Context, id, tokenID uuid.UUID, userAgent, ipAddress string, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID, exceptID uuid.UUID) error
+	DenyToken(ctx context.Context, tokenID uuid.UUID, expiresAt time.Time) error
+	IsTokenDenied(ctx context.Context, tokenID uuid.UUID) (bool, error)
+}
+
+type MongoSessionRepo struct {
+	sessions      *mongo.Collection
+	revokedTokens *mongo.Collection
+}
+
+func NewMongoSessionRepository(db *mongo.Database) SessionRepository {
+	return &MongoSessionRepo{
+		sessions:      db.Collection("sessions"),
+		revokedTokens: db.Collection("revoked_tokens"),
+	}
+}
+
+// CreateSessionIndexes adds the TTL indexes that let MongoDB remove expired sessions and denylist entries.
+func CreateSessionIndexes(ctx context.Context, db *mongo.Database) error {
+	ttl := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := db.Collection("sessions").Indexes().CreateOne(ctx, ttl); err != nil {
+		return err
+	}
+	if _, err := db.Collection("revoked_tokens").Indexes().CreateOne(ctx, ttl); err != nil {
+		return err
+	}
+
+	_, err := db.Collection("sessions").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}})
+	return err
+}
+
+// CreateSession stores a new session.
+func (r *MongoSessionRepo) CreateSession(ctx context.Context, session *types.SessionType) error {
+	_, err := r.sessions.InsertOne(ctx, session)
+	return err
+}
+
+// FindSessionByID finds a session by ID.
+func (r *MongoSessionRepo) FindSessionByID(ctx context.Context, id uuid.UUID) (*types.SessionType, error) {
+	var session types.SessionType
+	err := r.sessions.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("session not found")
+	}
+	return &session, err
+}
+
+// ListUserSessions lists the active sessions of a superuser, most recently used first.
+func (r *MongoSessionRepo) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]*types.SessionType, error) {
+	filter := bson.M{"user_id": userID, "revoked": false, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+
+	cursor, err := r.sessions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*types.SessionType
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchSession records the access token issued by a refresh and extends the session expiry.
+func (r *MongoSessionRepo) TouchSession(ctx context.Context, id, tokenID uuid.UUID, lastSeenAt, expiresAt time.Time) error {
+	update := bson.M{"$set": bson.M{"token_id": tokenID, "last_seen_at": lastSeenAt, "expires_at": expiresAt}}
+	_, err := r.sessions.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UpdateSessionActivity records the client and token of the latest authenticated request of a session.
+func (r *MongoSessionRepo) UpdateSessionActivity(ctx context.Context, id, tokenID uuid.UUID, userAgent, ipAddress string, lastSeenAt time.Time) error {
+	update := bson.M{"$set": bson.M{"token_id": tokenID, "user_agent": userAgent, "ip_address": ipAddress, "last_seen_at": lastSeenAt}}
+	_, err := r.sessions.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// RevokeSession revokes a single session.
+func (r *MongoSessionRepo) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	_, err := r.sessions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+// RevokeUserSessions revokes every session of a superuser except exceptID, which may be uuid.Nil.
+func (r *MongoSessionRepo) RevokeUserSessions(ctx context.Context, userID, exceptID uuid.UUID) error {
+	filter := bson.M{"user_id": userID, "_id": bson.M{"$ne": exceptID}}
+	_, err := r.sessions.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+// DenyToken adds a token ID to the denylist until the token expires.
+func (r *MongoSessionRepo) DenyToken(ctx context.Context, tokenID uuid.UUID, expiresAt time.Time) error {
+	opts := options.Update().SetUpsert(true)
+	_, err := r.revokedTokens.UpdateOne(ctx, bson.M{"_id": tokenID}, bson.M{"$set": bson.M{"expires_at": expiresAt}}, opts)
+	return err
+}
+
+// IsTokenDenied reports whether a token ID is on the denylist.
+func (r *MongoSessionRepo) IsTokenDenied(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	count, err := r.revokedTokens.CountDocuments(ctx, bson.M{"_id": tokenID}, options.Count().SetLimit(1))
+	return count > 0, err
+}
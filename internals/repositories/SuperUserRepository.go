@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,14 +22,26 @@ type SuperuserRepository interface {
 	FindSuperuserByResetToken(ctx context.Context, token string) (*types.SuperUserType, error)
 	DeleteSuperuserByID(ctx context.Context, id uuid.UUID) error
 	ListSuperusers(ctx context.Context, limit, skip int64) ([]*types.SuperUserType, error)
-	UpdateResetToken(ctx context.Context, id uuid.UUID, token string) error
+	UpdateResetToken(ctx context.Context, id uuid.UUID, token string, expiresAt int64) error
+	ConsumeResetToken(ctx context.Context, token string) (*types.SuperUserType, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	UpdateVerifySentAt(ctx context.Context, id uuid.UUID, sentAt int64) error
 	GetRoleByID(ctx context.Context, id uuid.UUID) (string, error)
 	Enable2FA(ctx context.Context, id uuid.UUID, isEnabled bool) error
 	SearchSuperusers(ctx context.Context, searchQuery string) ([]*types.SuperUserType, error)
 	SoftDeleteSuperuser(ctx context.Context, id uuid.UUID) error
+	RestoreSuperuser(ctx context.Context, id uuid.UUID) error
 	FindAll2FAEnabledSuperusers(ctx context.Context) ([]*types.SuperUserType, error)
 	UpdateSuperuserRole(ctx context.Context, id uuid.UUID, role string) error
 	BulkUpdateSuperusers(ctx context.Context, ids []uuid.UUID, updates map[string]interface{}) error
+	RecordFailedLogin(ctx context.Context, id uuid.UUID) (int, error)
+	LockSuperuser(ctx context.Context, id uuid.UUID, lockedUntil int64) error
+	UnlockSuperuser(ctx context.Context, id uuid.UUID) error
+	RecordLogin(ctx context.Context, id uuid.UUID, loginAt int64, ipAddress string) error
+	UpdateTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error
+	UpdateTOTPLastUsedStep(ctx context.Context, id uuid.UUID, step int64) error
+	UpdateRecoveryCodes(ctx context.Context, id uuid.UUID, codes []types.RecoveryCode) error
+	ConsumeRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error
 }
 
 type MongoSuperuserRepo struct {
@@ -77,10 +90,11 @@ func (r *MongoSuperuserRepo) UpdateSuperuser(ctx context.Context, superuser *typ
 	filter := bson.M{"_id": superuser.ID}
 	update := bson.M{
 		"$set": bson.M{
-			"username":       superuser.Username,
-			"password":       superuser.Password,
-			"updated_at":     time.Now().Unix(),
-			"is_2fa_enabled": superuser.Is2FAEnabled,
+			"username":         superuser.Username,
+			"password":         superuser.Password,
+			"password_history": superuser.PasswordHistory,
+			"updated_at":       time.Now().Unix(),
+			"is_2fa_enabled":   superuser.Is2FAEnabled,
 		},
 	}
 	_, err := r.db.UpdateOne(ctx, filter, update)
@@ -134,6 +148,7 @@ func (r *MongoSuperuserRepo) ListSuperusers(ctx context.Context, limit, skip int
 	findOptions := options.Find()
 	findOptions.SetLimit(limit)
 	findOptions.SetSkip(skip)
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
 
 	cursor, err := r.db.Find(ctx, bson.M{}, findOptions)
 	if err != nil {
@@ -145,14 +160,27 @@ func (r *MongoSuperuserRepo) ListSuperusers(ctx context.Context, limit, skip int
 	return superusers, nil
 }
 
-// UpdateResetToken updates the reset token for a superuser.
-func (r *MongoSuperuserRepo) UpdateResetToken(ctx context.Context, id uuid.UUID, token string) error {
+// UpdateResetToken updates the reset token and its expiry for a superuser.
+func (r *MongoSuperuserRepo) UpdateResetToken(ctx context.Context, id uuid.UUID, token string, expiresAt int64) error {
 	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"reset_token": token, "updated_at": time.Now().Unix()}}
+	update := bson.M{"$set": bson.M{"reset_token": token, "reset_token_expiry": expiresAt, "updated_at": time.Now().Unix()}}
 	_, err := r.db.UpdateOne(ctx, filter, update)
 	return err
 }
 
+// ConsumeResetToken finds the superuser owning an unexpired reset token and clears the token in one step,
+// so the token can only be used once.
+func (r *MongoSuperuserRepo) ConsumeResetToken(ctx context.Context, token string) (*types.SuperUserType, error) {
+	var superuser types.SuperUserType
+	filter := bson.M{"reset_token": token, "reset_token_expiry": bson.M{"$gt": time.Now().Unix()}}
+	update := bson.M{"$set": bson.M{"reset_token": "", "reset_token_expiry": 0, "updated_at": time.Now().Unix()}}
+	err := r.db.FindOneAndUpdate(ctx, filter, update).Decode(&superuser)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("superuser not found")
+	}
+	return &superuser, err
+}
+
 // Enable2FA enables or disables 2FA for a superuser.
 func (r *MongoSuperuserRepo) Enable2FA(ctx context.Context, id uuid.UUID, isEnabled bool) error {
 	filter := bson.M{"_id": id}
@@ -169,17 +197,70 @@ func (r *MongoSuperuserRepo) SoftDeleteSuperuser(ctx context.Context, id uuid.UU
 	return err
 }
 
+// RestoreSuperuser clears the archived flag set by SoftDeleteSuperuser.
+func (r *MongoSuperuserRepo) RestoreSuperuser(ctx context.Context, id uuid.UUID) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"archived": false, "updated_at": time.Now().Unix()}}
+	_, err := r.db.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RecordFailedLogin counts a failed login and returns the number of failures since the last success or lockout.
+func (r *MongoSuperuserRepo) RecordFailedLogin(ctx context.Context, id uuid.UUID) (int, error) {
+	var superuser types.SuperUserType
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.db.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failed_attempts": 1}}, opts).Decode(&superuser)
+	if err != nil {
+		return 0, err
+	}
+	return superuser.FailedAttempts, nil
+}
+
+// LockSuperuser refuses logins until lockedUntil and starts counting failures again.
+func (r *MongoSuperuserRepo) LockSuperuser(ctx context.Context, id uuid.UUID, lockedUntil int64) error {
+	update := bson.M{
+		"$set": bson.M{"locked_until": lockedUntil, "failed_attempts": 0},
+		"$inc": bson.M{"lockout_count": 1},
+	}
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UnlockSuperuser lifts a lockout and clears the failure counters.
+func (r *MongoSuperuserRepo) UnlockSuperuser(ctx context.Context, id uuid.UUID) error {
+	update := bson.M{"$set": bson.M{"locked_until": 0, "failed_attempts": 0, "lockout_count": 0}}
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// RecordLogin stores the time and IP of a successful login and clears the failure counters.
+func (r *MongoSuperuserRepo) RecordLogin(ctx context.Context, id uuid.UUID, loginAt int64, ipAddress string) error {
+	update := bson.M{"$set": bson.M{
+		"last_login_at":   loginAt,
+		"last_login_ip":   ipAddress,
+		"locked_until":    0,
+		"failed_attempts": 0,
+		"lockout_count":   0,
+	}}
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
 // SearchSuperusers allows partial search by full_name, username, or email.
 func (r *MongoSuperuserRepo) SearchSuperusers(ctx context.Context, searchQuery string) ([]*types.SuperUserType, error) {
 	var superusers []*types.SuperUserType
+
+	// Match the query literally, it comes straight from user input
+	pattern := regexp.QuoteMeta(searchQuery)
 	filter := bson.M{
 		"$or": []bson.M{
-			{"full_name": bson.M{"$regex": searchQuery, "$options": "i"}},
-			{"username": bson.M{"$regex": searchQuery, "$options": "i"}},
-			{"email": bson.M{"$regex": searchQuery, "$options": "i"}},
+			{"full_name": bson.M{"$regex": pattern, "$options": "i"}},
+			{"username": bson.M{"$regex": pattern, "$options": "i"}},
+			{"email": bson.M{"$regex": pattern, "$options": "i"}},
 		},
 	}
-	cursor, err := r.db.Find(ctx, filter)
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -218,3 +299,68 @@ func (r *MongoSuperuserRepo) BulkUpdateSuperusers(ctx context.Context, ids []uui
 	_, err := r.db.UpdateMany(ctx, filter, update)
 	return err
 }
+
+// UpdateTOTPSecret stores a new TOTP secret for a superuser and resets the replay counter.
+func (r *MongoSuperuserRepo) UpdateTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"totp_secret": secret, "totp_last_used_step": 0, "updated_at": time.Now().Unix()}}
+	_, err := r.db.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// UpdateTOTPLastUsedStep records the last accepted TOTP time step.
+// The update only succeeds when the step is newer than the stored one, so a code cannot be replayed concurrently.
+func (r *MongoSuperuserRepo) UpdateTOTPLastUsedStep(ctx context.Context, id uuid.UUID, step int64) error {
+	filter := bson.M{"_id": id, "totp_last_used_step": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"totp_last_used_step": step, "updated_at": time.Now().Unix()}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("2FA code has already been used")
+	}
+	return nil
+}
+
+// UpdateRecoveryCodes replaces the stored recovery codes of a superuser.
+func (r *MongoSuperuserRepo) UpdateRecoveryCodes(ctx context.Context, id uuid.UUID, codes []types.RecoveryCode) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"recovery_codes": codes, "updated_at": time.Now().Unix()}}
+	_, err := r.db.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used.
+// Matching and marking happen in a single update so a code can only be consumed once.
+func (r *MongoSuperuserRepo) ConsumeRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	filter := bson.M{
+		"_id":            id,
+		"recovery_codes": bson.M{"$elemMatch": bson.M{"hash": hash, "used_at": 0}},
+	}
+	update := bson.M{"$set": bson.M{"recovery_codes.$.used_at": time.Now().Unix(), "updated_at": time.Now().Unix()}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("invalid recovery code")
+	}
+	return nil
+}
+
+// MarkEmailVerified records that a superuser confirmed their email address.
+func (r *MongoSuperuserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"email_verified": true, "updated_at": time.Now().Unix()}}
+	_, err := r.db.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// UpdateVerifySentAt records when the last verification email was sent to a superuser.
+func (r *MongoSuperuserRepo) UpdateVerifySentAt(ctx context.Context, id uuid.UUID, sentAt int64) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"verify_sent_at": sentAt, "updated_at": time.Now().Unix()}}
+	_, err := r.db.UpdateOne(ctx, filter, update)
+	return err
+}
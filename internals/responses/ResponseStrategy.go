@@ -2,9 +2,9 @@ package responses
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
 )
 
 // ResponseStrategy defines the interface for responding with different formats.
@@ -27,13 +27,12 @@ type HTMLResponseStrategy struct {
 }
 
 func (r *HTMLResponseStrategy) Respond(c *gin.Context, data interface{}, status int) {
-	// Log the data for debugging
-	fmt.Printf("Data passed to Respond: %+v\n", data)
+	logger := logging.FromContext(c.Request.Context())
 
 	// Check if data is a map[string]interface{}
 	dataMap, ok := data.(map[string]interface{})
 	if !ok {
-		fmt.Println("Error: data is not a map[string]interface{}")
+		logger.Error("HTML response data is not a map", "type", fmt.Sprintf("%T", data))
 		c.HTML(status, "error.html", gin.H{"error": "Internal Server Error"})
 		return
 	}
@@ -41,21 +40,28 @@ func (r *HTMLResponseStrategy) Respond(c *gin.Context, data interface{}, status
 	// Check if template key exists and is a string
 	templateName, exists := dataMap["template"]
 	if !exists {
-		fmt.Println("Error: template key not found in data")
+		logger.Error("HTML response has no template")
 		c.HTML(status, "error.html", gin.H{"error": "Template not specified"})
 		return
 	}
 
 	templateNameStr, isString := templateName.(string)
 	if !isString {
-		fmt.Println("Error: template name is not a string")
+		logger.Error("HTML response template name is not a string", "type", fmt.Sprintf("%T", templateName))
 		c.HTML(status, "error.html", gin.H{"error": "Invalid template name"})
 		return
 	}
 
-	fmt.Printf("Using template: %s\n", templateNameStr)
+	// Only the template is logged, the data may hold secrets such as recovery codes
+	logger.Debug("Rendering template", "template", templateNameStr, "status", status)
 	// Remove the "template" key before passing data to c.HTML
 	delete(dataMap, "template")
+
+	// Pages send the CSRF token back with every HTMX request
+	if _, exists := dataMap["csrf_token"]; !exists {
+		dataMap["csrf_token"] = c.GetString("csrfToken")
+	}
+
 	c.HTML(status, templateNameStr, dataMap)
 }
 
@@ -71,12 +77,12 @@ func (r *DefaultResponseStrategy) Respond(c *gin.Context, data interface{}, stat
 func GetResponseStrategy(c *gin.Context) ResponseStrategy {
 	strategy, exists := c.Get("responseStrategy")
 	if !exists {
-		log.Println("No response strategy set, using default")
+		logging.FromContext(c.Request.Context()).Warn("No response strategy set, using default")
 		return &DefaultResponseStrategy{}
 	}
 	if responseStrategy, ok := strategy.(ResponseStrategy); ok {
 		return responseStrategy
 	}
-	log.Println("Invalid response strategy set, using default")
+	logging.FromContext(c.Request.Context()).Warn("Invalid response strategy set, using default")
 	return &DefaultResponseStrategy{}
 }
@@ -4,10 +4,25 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lordofthemind/htmx_GO/internals/handlers"
 	"github.com/lordofthemind/htmx_GO/pkgs/middlewares"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
 	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
 )
 
-func RegisterSuperuserRoutes(router *gin.Engine, superuserHandler *handlers.SuperuserHandler, tokenManager tokens.TokenManager) {
+func RegisterSuperuserRoutes(router *gin.Engine, superuserHandler *handlers.SuperuserHandler, tokenManager tokens.TokenManager, sessionValidator middlewares.SessionValidator, sessionRefresher middlewares.SessionRefresher, sessionTracker middlewares.SessionTracker, apiKeyAuthenticator middlewares.APIKeyAuthenticator, permissionResolver middlewares.PermissionResolver, rateLimitStore middlewares.RateLimitStore) {
+	// Shorthand for declaring the permissions a route requires
+	require := func(permissions ...rbac.Permission) gin.HandlerFunc {
+		return middlewares.RequirePermission(permissionResolver, permissions...)
+	}
+	// Shorthand for applying a rate limit policy from the configuration
+	limit := func(policy string) gin.HandlerFunc {
+		return middlewares.RateLimitMiddleware(rateLimitStore, policy)
+	}
+	// Account security routes cannot be reached with an API key
+	sessionOnly := middlewares.RequireSession()
+
+	// Public keys for verifying asymmetric tokens
+	router.GET("/.well-known/jwks.json", superuserHandler.JWKSHandler)
+
 	// Group for superuser-related routes
 	superuserRoutes := router.Group("/superuser")
 	{
@@ -15,33 +30,77 @@ func RegisterSuperuserRoutes(router *gin.Engine, superuserHandler *handlers.Supe
 		superuserRoutes.GET("/", superuserHandler.IndexRender)
 		superuserRoutes.GET("/register", superuserHandler.RegisterRender)
 		superuserRoutes.GET("/login", superuserHandler.LoginRender)
-		superuserRoutes.POST("/register", superuserHandler.RegisterSuperuserHandler)
-		superuserRoutes.POST("/login", superuserHandler.LoginSuperuserHandler)
+		superuserRoutes.POST("/register", limit("register"), superuserHandler.RegisterSuperuserHandler)
+		superuserRoutes.POST("/login", limit("login"), superuserHandler.LoginSuperuserHandler)
+		superuserRoutes.POST("/login/2fa", limit("login"), superuserHandler.LoginVerify2FAHandler)
+		superuserRoutes.POST("/token/refresh", limit("token_refresh"), superuserHandler.TokenRefreshHandler)
+
+		// Email verification routes
+		superuserRoutes.GET("/verify-email/:token", superuserHandler.VerifyEmailHandler)
+		superuserRoutes.POST("/verify-email/resend", limit("verify_email_resend"), superuserHandler.VerifyEmailResendHandler)
 
-		// Apply JWTAuthMiddleware to protect routes
+		// Password reset routes are public since a locked-out superuser cannot authenticate
+		superuserRoutes.GET("/password-reset-request", superuserHandler.PasswordResetRequestRender)
+		superuserRoutes.POST("/password-reset-request", limit("password_reset"), superuserHandler.PasswordResetRequestHandler)
+		superuserRoutes.GET("/password-reset/:token", superuserHandler.PasswordResetRender)
+		superuserRoutes.POST("/password-reset/:token", limit("password_reset"), superuserHandler.PasswordResetHandler)
+
+		// Protected routes accept a personal API key or an access token
 		protectedRoutes := superuserRoutes.Group("/")
-		protectedRoutes.Use(middlewares.AuthTokenMiddleware(tokenManager))
+		protectedRoutes.Use(middlewares.APIKeyMiddleware(apiKeyAuthenticator))
+		protectedRoutes.Use(middlewares.AuthTokenMiddleware(tokenManager, sessionValidator, sessionRefresher, sessionTracker))
+		protectedRoutes.Use(limit("authenticated"))
 		{
 			// Protected routes
-			protectedRoutes.GET("/dashboard", superuserHandler.DashboardSuperuserHandler)
-			protectedRoutes.GET("/logout", superuserHandler.LogoutSuperuserHandler)
+			protectedRoutes.GET("/dashboard", require(rbac.PermDashboardView), superuserHandler.DashboardSuperuserHandler)
+			protectedRoutes.GET("/activity", require(rbac.PermDashboardView), superuserHandler.ActivityTimelineHandler)
+			protectedRoutes.GET("/logout", sessionOnly, superuserHandler.LogoutSuperuserHandler)
 			protectedRoutes.GET("/test", superuserHandler.TestTemplate)
 
 			// Profile routes
-			protectedRoutes.GET("/profile", superuserHandler.ProfileViewHandler)
-			protectedRoutes.POST("/profile", superuserHandler.ProfileUpdateHandler)
+			protectedRoutes.GET("/profile", require(rbac.PermProfileRead), superuserHandler.ProfileViewHandler)
+			protectedRoutes.POST("/profile", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.ProfileUpdateHandler)
+
+			// Active sessions of the current superuser
+			protectedRoutes.GET("/sessions", sessionOnly, require(rbac.PermProfileRead), superuserHandler.SessionsListHandler)
+			protectedRoutes.DELETE("/sessions/:id", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.RevokeSessionHandler)
+			protectedRoutes.POST("/sessions/revoke-others", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.RevokeOtherSessionsHandler)
 
-			// Password reset routes
-			protectedRoutes.GET("/password-reset-request", superuserHandler.PasswordResetRequestHandler)
-			protectedRoutes.POST("/password-reset/:token", superuserHandler.PasswordResetHandler)
+			// Personal API keys
+			protectedRoutes.GET("/api-keys", sessionOnly, require(rbac.PermProfileRead), superuserHandler.APIKeysListHandler)
+			protectedRoutes.POST("/api-keys", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.APIKeyCreateHandler)
+			protectedRoutes.DELETE("/api-keys/:id", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.APIKeyRevokeHandler)
 
 			// 2FA routes
-			protectedRoutes.GET("/enable-2fa", superuserHandler.Enable2FAHandler)
-			protectedRoutes.POST("/verify-2fa", superuserHandler.Verify2FAHandler)
+			protectedRoutes.GET("/enable-2fa", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.Enable2FAViewHandler)
+			protectedRoutes.POST("/enable-2fa", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.Enable2FAHandler)
+			protectedRoutes.POST("/verify-2fa", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.Verify2FAHandler)
+			protectedRoutes.GET("/recovery-codes", sessionOnly, require(rbac.PermProfileRead), superuserHandler.RecoveryCodesViewHandler)
+			protectedRoutes.POST("/recovery-codes", sessionOnly, require(rbac.PermProfileWrite), superuserHandler.RecoveryCodesRegenerateHandler)
 
 			// File upload and download
-			protectedRoutes.POST("/upload", superuserHandler.FileUploadHandler)
-			protectedRoutes.GET("/download/:filename", superuserHandler.FileDownloadHandler)
+			protectedRoutes.POST("/upload", require(rbac.PermFilesUpload), superuserHandler.FileUploadHandler)
+			protectedRoutes.GET("/download/:filename", require(rbac.PermFilesDownload), superuserHandler.FileDownloadHandler)
+
+			// Role overview
+			protectedRoutes.GET("/roles", require(rbac.PermRolesManage), superuserHandler.RoleManagementHandler)
+
+			// Superuser administration
+			adminRoutes := protectedRoutes.Group("/admin")
+			{
+				adminRoutes.GET("/superusers", require(rbac.PermSuperusersRead), superuserHandler.AdminListSuperusersHandler)
+				adminRoutes.POST("/superusers/bulk", require(rbac.PermSuperusersWrite), superuserHandler.AdminBulkActionHandler)
+				adminRoutes.POST("/superusers/:id/role", require(rbac.PermRolesManage), superuserHandler.AdminUpdateRoleHandler)
+				adminRoutes.POST("/superusers/:id/archive", require(rbac.PermSuperusersWrite), superuserHandler.AdminArchiveSuperuserHandler)
+				adminRoutes.POST("/superusers/:id/restore", require(rbac.PermSuperusersWrite), superuserHandler.AdminRestoreSuperuserHandler)
+				adminRoutes.POST("/superusers/:id/unlock", require(rbac.PermSuperusersWrite), superuserHandler.AdminUnlockSuperuserHandler)
+				adminRoutes.DELETE("/superusers/:id", require(rbac.PermSuperusersWrite), superuserHandler.AdminDeleteSuperuserHandler)
+
+				// Audit log viewer and export
+				adminRoutes.GET("/audit", require(rbac.PermAuditRead), superuserHandler.AuditLogsHandler)
+				adminRoutes.GET("/audit/export", require(rbac.PermAuditRead), superuserHandler.AuditExportHandler)
+				adminRoutes.GET("/audit/verify", require(rbac.PermAuditRead), superuserHandler.AuditVerifyHandler)
+			}
 		}
 	}
 }
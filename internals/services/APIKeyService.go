@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+)
+
+var (
+	ErrAPIKeyInvalid  = errors.New("api key is invalid or has been revoked")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyScope    = errors.New("api key scopes must be permissions you hold")
+	ErrAPIKeyLimit    = errors.New("api key limit reached, revoke an unused key first")
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string) (*types.APIKeyType, string, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]*types.APIKeyType, error)
+	AvailableScopes(ctx context.Context, userID uuid.UUID) ([]rbac.Permission, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	AuthenticateAPIKey(ctx context.Context, key, ipAddress string) (*tokens.Payload, rbac.PermissionSet, error)
+}
+
+type apiKeyService struct {
+	repo          repositories.APIKeyRepository
+	superuserRepo repositories.SuperuserRepository
+	policy        *rbac.Policy
+}
+
+func NewAPIKeyService(repo repositories.APIKeyRepository, superuserRepo repositories.SuperuserRepository, policy *rbac.Policy) APIKeyService {
+	return &apiKeyService{
+		repo:          repo,
+		superuserRepo: superuserRepo,
+		policy:        policy,
+	}
+}
+
+// CreateAPIKey creates an API key limited to the given scopes and returns it with the plain key,
+// which is not stored and cannot be shown again.
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string) (*types.APIKeyType, string, error) {
+	count, err := s.repo.CountUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if configs.APIKeyMaxPerUser > 0 && count >= int64(configs.APIKeyMaxPerUser) {
+		return nil, "", ErrAPIKeyLimit
+	}
+
+	// A key can never do more than its owner
+	available, err := s.AvailableScopes(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(scopes) == 0 || !containsAll(available, scopes) {
+		return nil, "", ErrAPIKeyScope
+	}
+
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, "", errors.New("failed to generate api key")
+	}
+	secret, _, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	plainKey := tokens.APIKeyTag + "_" + prefix + "_" + secret
+
+	key := &types.APIKeyType{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashOpaqueToken(plainKey),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, plainKey, nil
+}
+
+// ListAPIKeys lists the active API keys of a superuser.
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]*types.APIKeyType, error) {
+	return s.repo.ListUserAPIKeys(ctx, userID)
+}
+
+// AvailableScopes returns the permissions a superuser may grant to their API keys.
+func (s *apiKeyService) AvailableScopes(ctx context.Context, userID uuid.UUID) ([]rbac.Permission, error) {
+	superuser, err := s.superuserRepo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := s.policy.PermissionsFor(superuser.Role, superuser.PermissionGroups)
+	var scopes []rbac.Permission
+	for _, permission := range rbac.AllPermissions() {
+		if granted.Has(permission) {
+			scopes = append(scopes, permission)
+		}
+	}
+	return scopes, nil
+}
+
+// RevokeAPIKey revokes one of the superuser's API keys.
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	revoked, err := s.repo.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey checks an API key and returns a payload describing its owner, together with
+// the permissions the request may use: the key's scopes that its owner still holds.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key, ipAddress string) (*tokens.Payload, rbac.PermissionSet, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != tokens.APIKeyTag {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	stored, err := s.repo.FindAPIKeyByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(hashOpaqueToken(key))) != 1 || stored.Revoked {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	superuser, err := s.superuserRepo.FindSuperuserByID(ctx, stored.UserID)
+	if err != nil || superuser.Archived {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	// Scopes only narrow the owner's current permissions, so a role downgrade also limits the key
+	granted := s.policy.PermissionsFor(superuser.Role, superuser.PermissionGroups)
+	permissions := rbac.PermissionSet{}
+	for _, scope := range stored.Scopes {
+		if permission := rbac.Permission(scope); granted.Has(permission) {
+			permissions[permission] = struct{}{}
+		}
+	}
+
+	// Failing to count usage should not fail the request
+	if err := s.repo.RecordAPIKeyUsage(ctx, stored.ID, ipAddress, time.Now()); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record api key usage", "api_key_id", stored.ID, "error", err)
+	}
+
+	payload := &tokens.Payload{
+		ID:       stored.ID,
+		UserID:   superuser.ID,
+		Username: superuser.Email,
+		Role:     superuser.Role,
+		Purpose:  tokens.PurposeAPIKey,
+		IssuedAt: stored.CreatedAt,
+	}
+	return payload, permissions, nil
+}
+
+// containsAll reports whether every scope is one of the available permissions.
+func containsAll(available []rbac.Permission, scopes []string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, permission := range available {
+			if string(permission) == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
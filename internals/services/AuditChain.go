@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// auditVerifyBatch is the number of entries loaded at a time while verifying the chain.
+const auditVerifyBatch = 500
+
+// auditAppendAttempts bounds the retries when another instance appended to the chain first.
+const auditAppendAttempts = 5
+
+// AuditChainBreak describes the first link of the audit log hash chain that failed verification.
+type AuditChainBreak struct {
+	Sequence int64     `json:"sequence"`
+	EntryID  uuid.UUID `json:"entry_id"` // uuid.Nil when the break is not a stored entry, e.g. a removed one
+	Reason   string    `json:"reason"`
+}
+
+// AuditChainReport is the outcome of verifying the audit log hash chain.
+type AuditChainReport struct {
+	Intact        bool             `json:"intact"`
+	Entries       int              `json:"entries"`        // chained entries that were verified
+	FirstSequence int64            `json:"first_sequence"` // oldest retained entry, earlier ones expired
+	LastSequence  int64            `json:"last_sequence"`
+	Checkpoints   int              `json:"checkpoints"`
+	Break         *AuditChainBreak `json:"break,omitempty"`
+	VerifiedAt    time.Time        `json:"verified_at"`
+}
+
+// auditChainHead is the end of the chain new entries are appended to.
+type auditChainHead struct {
+	sequence  int64
+	hash      string
+	expiresAt time.Time
+}
+
+// auditHashedFields is the canonical form of an entry that is hashed. JSON keeps the field order fixed
+// and sorts metadata keys, so the hash can be recomputed from a stored entry. Metadata should only hold
+// strings, numbers, booleans and lists of them, which survive a round trip through the database unchanged.
+type auditHashedFields struct {
+	Sequence  int64                  `json:"sequence"`
+	PrevHash  string                 `json:"prev_hash"`
+	ID        string                 `json:"id"`
+	UserID    string                 `json:"user_id"`
+	Action    string                 `json:"action"`
+	Timestamp string                 `json:"timestamp"`
+	IPAddress string                 `json:"ip_address"`
+	UserAgent string                 `json:"user_agent"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	ExpiresAt string                 `json:"expires_at"`
+}
+
+// auditEntryHash returns the hex SHA-256 of an entry chained to PrevHash.
+func auditEntryHash(entry *types.UserActivityLog) (string, error) {
+	metadata := entry.Metadata
+	if len(metadata) == 0 {
+		metadata = nil // an empty map is not stored
+	}
+	encoded, err := json.Marshal(auditHashedFields{
+		Sequence:  entry.Sequence,
+		PrevHash:  entry.PrevHash,
+		ID:        entry.ID.String(),
+		UserID:    entry.UserID.String(),
+		Action:    entry.Action,
+		Timestamp: auditTime(entry.Timestamp),
+		IPAddress: entry.IPAddress,
+		UserAgent: entry.UserAgent,
+		Metadata:  metadata,
+		ExpiresAt: auditTime(entry.ExpiresAt),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit log entry: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditTime formats a time the same way whatever location the database decoded it in.
+func auditTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// auditMAC returns the hex HMAC-SHA256 of the given parts with a key derived from the audit chain key.
+// Entries and checkpoints use different purposes, so one can never pass for the other.
+func auditMAC(purpose string, parts ...string) string {
+	derived := hmac.New(sha256.New, []byte(configs.AuditChainKey))
+	derived.Write([]byte(purpose))
+	mac := hmac.New(sha256.New, derived.Sum(nil))
+	for _, part := range parts {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkpointMAC signs every field of a checkpoint except its ID, which includes the MAC of the checkpoint before it.
+func checkpointMAC(checkpoint *types.AuditCheckpoint) string {
+	return auditMAC("audit-checkpoint",
+		fmt.Sprint(checkpoint.Number),
+		fmt.Sprint(checkpoint.Sequence),
+		checkpoint.Hash,
+		auditTime(checkpoint.EntryExpiresAt),
+		auditTime(checkpoint.CreatedAt),
+		fmt.Sprint(checkpoint.Resumed),
+		checkpoint.PrevMAC,
+	)
+}
+
+// sealAuditEntry links an entry to the head of the chain and signs it.
+func sealAuditEntry(entry *types.UserActivityLog, head auditChainHead) error {
+	entry.Sequence = head.sequence + 1
+	entry.PrevHash = head.hash
+	hash, err := auditEntryHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	entry.MAC = auditMAC("audit-entry", hash)
+	return nil
+}
+
+// append stores an entry at the head of the chain. Callers must hold the chain lock.
+func (s *auditService) append(ctx context.Context, entry *types.UserActivityLog) error {
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		if !s.headLoaded {
+			if err := s.loadHead(ctx); err != nil {
+				return err
+			}
+		}
+		if err := sealAuditEntry(entry, s.head); err != nil {
+			return err
+		}
+		err := s.repo.CreateAuditLog(ctx, entry)
+		if err == repositories.ErrAuditSequenceTaken {
+			s.headLoaded = false // another instance appended first
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.head = auditChainHead{sequence: entry.Sequence, hash: entry.Hash, expiresAt: entry.ExpiresAt}
+		return nil
+	}
+	return fmt.Errorf("failed to append audit log entry after %d attempts: %w", auditAppendAttempts, repositories.ErrAuditSequenceTaken)
+}
+
+// loadHead reads the end of the chain. When every entry has expired the chain continues from the latest
+// checkpoint, so sequences are never reused.
+func (s *auditService) loadHead(ctx context.Context) error {
+	latest, err := s.repo.LatestAuditLog(ctx)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := s.repo.LatestAuditCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.head = auditChainHead{}
+	if latest != nil {
+		s.head = auditChainHead{sequence: latest.Sequence, hash: latest.Hash, expiresAt: latest.ExpiresAt}
+	}
+	if checkpoint != nil && checkpoint.Sequence > s.head.sequence {
+		s.head = auditChainHead{sequence: checkpoint.Sequence, hash: checkpoint.Hash, expiresAt: checkpoint.EntryExpiresAt}
+	}
+	s.headLoaded = true
+	return nil
+}
+
+// Checkpoint stores a signed checkpoint of the current head of the chain, linked to the previous checkpoint.
+// A checkpoint is stored even when nothing was appended since the last one, so removed checkpoints show up
+// as gaps. Every checkpoint is also written to the server log, a copy outside the database to compare against.
+func (s *auditService) Checkpoint(ctx context.Context) (*types.AuditCheckpoint, error) {
+	return s.checkpoint(ctx, false)
+}
+
+// checkpoint stores a checkpoint, resumed marks the first one after the server started.
+func (s *auditService) checkpoint(ctx context.Context, resumed bool) (*types.AuditCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		// Reload, other instances may have appended or checkpointed since this one last did
+		if err := s.loadHead(ctx); err != nil {
+			return nil, err
+		}
+		previous, err := s.repo.LatestAuditCheckpoint(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint := &types.AuditCheckpoint{
+			ID:             uuid.New(),
+			Number:         1,
+			Sequence:       s.head.sequence,
+			Hash:           s.head.hash,
+			EntryExpiresAt: s.head.expiresAt,
+			CreatedAt:      time.Now().UTC().Truncate(time.Millisecond),
+			Resumed:        resumed,
+		}
+		if previous != nil {
+			checkpoint.Number = previous.Number + 1
+			checkpoint.PrevMAC = previous.MAC
+		}
+		checkpoint.MAC = checkpointMAC(checkpoint)
+
+		err = s.repo.CreateAuditCheckpoint(ctx, checkpoint)
+		if err == repositories.ErrAuditCheckpointTaken {
+			continue // another instance checkpointed first
+		}
+		if err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("Audit checkpoint stored",
+			"number", checkpoint.Number,
+			"sequence", checkpoint.Sequence,
+			"hash", checkpoint.Hash,
+			"created_at", auditTime(checkpoint.CreatedAt),
+			"mac", checkpoint.MAC,
+		)
+		return checkpoint, nil
+	}
+	return nil, fmt.Errorf("failed to store audit checkpoint after %d attempts: %w", auditAppendAttempts, repositories.ErrAuditCheckpointTaken)
+}
+
+// StartCheckpoints stores a checkpoint right away and then every interval until ctx is done.
+func (s *auditService) StartCheckpoints(ctx context.Context, interval time.Duration) {
+	if _, err := s.checkpoint(ctx, true); err != nil {
+		logging.FromContext(ctx).Error("Failed to store audit log checkpoint", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Checkpoint(ctx); err != nil {
+					logging.FromContext(ctx).Error("Failed to store audit log checkpoint", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// VerifyAuditChain walks the hash chain from the oldest retained entry and reports the first broken link.
+// Every entry must match its hash and MAC and point at the hash of the entry before it, and every checkpoint
+// must be correctly signed, follow the checkpoint before it and match the entry it pinned. Checkpoints must
+// not be further apart than twice the checkpoint interval, except across a server restart, and the latest one
+// must be recent. Entries removed by the retention are expected, entries removed early are not.
+func (s *auditService) VerifyAuditChain(ctx context.Context) (*AuditChainReport, error) {
+	report := &AuditChainReport{VerifiedAt: time.Now().UTC()}
+	fail := func(sequence int64, entryID uuid.UUID, reason string) {
+		if report.Break == nil || sequence < report.Break.Sequence {
+			report.Break = &AuditChainBreak{Sequence: sequence, EntryID: entryID, Reason: reason}
+		}
+	}
+
+	checkpoints, err := s.repo.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Checkpoints = len(checkpoints)
+	pinned := map[int64]*types.AuditCheckpoint{}
+	valid := make([]*types.AuditCheckpoint, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		var before *types.AuditCheckpoint
+		if len(valid) > 0 {
+			before = valid[len(valid)-1]
+		}
+		if reason := checkCheckpointLink(checkpoint, before); reason != "" {
+			fail(checkpoint.Sequence, uuid.Nil, reason)
+			break // later checkpoints cannot be trusted
+		}
+		pinned[checkpoint.Sequence] = checkpoint
+		valid = append(valid, checkpoint)
+	}
+
+	now := time.Now()
+	if sequence, reason := checkpointGap(valid, configs.AuditCheckpointInterval, now); reason != "" {
+		fail(sequence, uuid.Nil, reason)
+	}
+
+	var previous, first *types.UserActivityLog
+walk:
+	for {
+		var after int64
+		if previous != nil {
+			after = previous.Sequence
+		}
+		entries, err := s.repo.ListAuditLogsAfter(ctx, after, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, entry := range entries {
+			if previous == nil {
+				if reason := missingBefore(entry, valid, now); reason != "" {
+					fail(entry.Sequence, entry.ID, reason)
+					break walk
+				}
+			}
+			if reason := checkAuditLink(entry, previous); reason != "" {
+				fail(entry.Sequence, entry.ID, reason)
+				break walk
+			}
+			if checkpoint, ok := pinned[entry.Sequence]; ok && checkpoint.Hash != entry.Hash {
+				fail(entry.Sequence, entry.ID, fmt.Sprintf("entry does not match checkpoint %s", checkpoint.ID))
+				break walk
+			}
+			if previous == nil {
+				report.FirstSequence = entry.Sequence
+				first = entry
+			}
+			previous = entry
+			report.Entries++
+			report.LastSequence = entry.Sequence
+		}
+	}
+
+	// The newest checkpoints prove how long the chain was, unless their entries have legitimately expired
+	if report.Break == nil && len(valid) > 0 {
+		last := valid[len(valid)-1]
+		if last.Sequence > report.LastSequence && !auditExpired(last.EntryExpiresAt, now) {
+			fail(report.LastSequence+1, uuid.Nil, fmt.Sprintf("%s but checkpoint %s pins them", missingEntries(report.LastSequence+1, last.Sequence), last.ID))
+		}
+	}
+
+	// Without any checkpoint the chain must be younger than the first one is due
+	interval := configs.AuditCheckpointInterval
+	if len(checkpoints) == 0 && first != nil && interval > 0 && now.Sub(first.Timestamp) > 2*interval {
+		fail(first.Sequence, first.ID, "no checkpoint was stored for this entry or any later one")
+	}
+
+	report.Intact = report.Break == nil
+	return report, nil
+}
+
+// checkCheckpointLink verifies a checkpoint's signature and its link to the checkpoint before it.
+// The first checkpoint must start the checkpoint chain, so removing the oldest checkpoints is noticed.
+func checkCheckpointLink(checkpoint, previous *types.AuditCheckpoint) string {
+	if !hmac.Equal([]byte(checkpoint.MAC), []byte(checkpointMAC(checkpoint))) {
+		return fmt.Sprintf("checkpoint %d has an invalid signature", checkpoint.Number)
+	}
+	if previous == nil {
+		if checkpoint.Number != 1 || checkpoint.PrevMAC != "" {
+			return fmt.Sprintf("checkpoints before %d are missing", checkpoint.Number)
+		}
+		return ""
+	}
+	if checkpoint.Number != previous.Number+1 {
+		return fmt.Sprintf("checkpoints %d to %d are missing", previous.Number+1, checkpoint.Number-1)
+	}
+	if checkpoint.PrevMAC != previous.MAC {
+		return fmt.Sprintf("checkpoint %d does not follow checkpoint %d", checkpoint.Number, previous.Number)
+	}
+	if checkpoint.Sequence < previous.Sequence {
+		return fmt.Sprintf("checkpoint %d pins an older entry than checkpoint %d", checkpoint.Number, previous.Number)
+	}
+	return ""
+}
+
+// checkpointGap reports the first stretch without checkpoints longer than twice the interval, and the entry
+// sequence after which entries may be missing. A resumed checkpoint may follow a longer gap, the server was
+// down. A latest checkpoint older than that means newer ones were removed, or the server is not running.
+func checkpointGap(checkpoints []*types.AuditCheckpoint, interval time.Duration, now time.Time) (int64, string) {
+	if interval <= 0 || len(checkpoints) == 0 {
+		return 0, ""
+	}
+	limit := 2 * interval
+	for i := 1; i < len(checkpoints); i++ {
+		previous, checkpoint := checkpoints[i-1], checkpoints[i]
+		if !checkpoint.Resumed && checkpoint.CreatedAt.Sub(previous.CreatedAt) > limit {
+			return previous.Sequence + 1, fmt.Sprintf("no checkpoint between checkpoints %d and %d for %s",
+				previous.Number, checkpoint.Number, checkpoint.CreatedAt.Sub(previous.CreatedAt).Round(time.Second))
+		}
+	}
+	last := checkpoints[len(checkpoints)-1]
+	if now.Sub(last.CreatedAt) > limit {
+		return last.Sequence + 1, fmt.Sprintf("no checkpoint since checkpoint %d at %s, newer ones were removed or the server is not running",
+			last.Number, auditTime(last.CreatedAt))
+	}
+	return 0, ""
+}
+
+// checkAuditLink verifies an entry's own hash and MAC and its link to the entry before it.
+func checkAuditLink(entry, previous *types.UserActivityLog) string {
+	hash, err := auditEntryHash(entry)
+	if err != nil || hash != entry.Hash {
+		return "entry contents do not match its hash"
+	}
+	if !hmac.Equal([]byte(entry.MAC), []byte(auditMAC("audit-entry", entry.Hash))) {
+		return "entry hash is not signed with the audit key"
+	}
+	if previous == nil {
+		return ""
+	}
+	if entry.Sequence != previous.Sequence+1 {
+		return missingEntries(previous.Sequence+1, entry.Sequence-1)
+	}
+	if entry.PrevHash != previous.Hash {
+		return fmt.Sprintf("previous hash does not match entry %d", previous.Sequence)
+	}
+	return ""
+}
+
+// missingBefore reports whether entries before the oldest retained one were removed early. They may only be
+// gone once the retention expired them, which the latest checkpoint before the entry proves: the entry it
+// pinned must have expired, and entries appended after it expire no sooner than the retention after it was
+// taken. Without such a checkpoint the chain must start at sequence 1 when entries are kept forever.
+func missingBefore(first *types.UserActivityLog, checkpoints []*types.AuditCheckpoint, now time.Time) string {
+	if first.Sequence == 1 {
+		if first.PrevHash != "" {
+			return "first entry does not start the chain"
+		}
+		return ""
+	}
+
+	var before *types.AuditCheckpoint
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Sequence < first.Sequence {
+			before = checkpoint
+		}
+	}
+	switch {
+	case before != nil && before.Sequence > 0 && !auditExpired(before.EntryExpiresAt, now):
+		return fmt.Sprintf("entries up to %d were removed before they expired", first.Sequence-1)
+	case before != nil && before.Sequence < first.Sequence-1 && configs.AuditRetention > 0 &&
+		now.Before(before.CreatedAt.Add(configs.AuditRetention)):
+		return fmt.Sprintf("entries %d to %d were removed before they expired", before.Sequence+1, first.Sequence-1)
+	case (before == nil || before.Sequence == 0) && configs.AuditRetention == 0:
+		return missingEntries(1, first.Sequence-1)
+	case before != nil && before.Sequence == first.Sequence-1 && before.Hash != first.PrevHash:
+		return fmt.Sprintf("previous hash does not match checkpoint %s", before.ID)
+	}
+	return ""
+}
+
+// missingEntries describes a range of removed entries.
+func missingEntries(from, to int64) string {
+	if from == to {
+		return fmt.Sprintf("entry %d is missing", from)
+	}
+	return fmt.Sprintf("entries %d to %d are missing", from, to)
+}
+
+// auditExpired reports whether the retention has removed, or may by now have removed, an entry.
+func auditExpired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
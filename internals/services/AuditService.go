@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+)
+
+// Audited actions
+const (
+	AuditLogin                  = "login"
+	AuditLoginFailed            = "login_failed"
+	AuditLogout                 = "logout"
+	AuditProfileUpdated         = "profile_updated"
+	AuditPasswordChanged        = "password_changed"
+	AuditPasswordResetRequested = "password_reset_requested"
+	AuditPasswordReset          = "password_reset"
+	AuditRoleChanged            = "role_changed"
+	AuditSuperuserArchived      = "superuser_archived"
+	AuditSuperuserRestored      = "superuser_restored"
+	AuditSuperuserUnlocked      = "superuser_unlocked"
+	AuditSuperuserDeleted       = "superuser_deleted"
+	AuditAPIKeyCreated          = "api_key_created"
+	AuditAPIKeyRevoked          = "api_key_revoked"
+	AuditTwoFactorEnabled       = "2fa_enabled"
+	AuditRecoveryCodesRenewed   = "recovery_codes_regenerated"
+	AuditFileUploaded           = "file_uploaded"
+	AuditFileDownloaded         = "file_downloaded"
+)
+
+// AuditActions lists every audited action, for the viewer's action filter.
+func AuditActions() []string {
+	return []string{
+		AuditLogin,
+		AuditLoginFailed,
+		AuditLogout,
+		AuditProfileUpdated,
+		AuditPasswordChanged,
+		AuditPasswordResetRequested,
+		AuditPasswordReset,
+		AuditRoleChanged,
+		AuditSuperuserArchived,
+		AuditSuperuserRestored,
+		AuditSuperuserUnlocked,
+		AuditSuperuserDeleted,
+		AuditAPIKeyCreated,
+		AuditAPIKeyRevoked,
+		AuditTwoFactorEnabled,
+		AuditRecoveryCodesRenewed,
+		AuditFileUploaded,
+		AuditFileDownloaded,
+	}
+}
+
+type AuditService interface {
+	Record(ctx context.Context, userID uuid.UUID, action, ipAddress, userAgent string, metadata map[string]interface{}) error
+	ListUserActivity(ctx context.Context, userID uuid.UUID, before time.Time, page, pageSize int) ([]*types.UserActivityLog, bool, error)
+	ListAuditLogsPage(ctx context.Context, filter types.UserActivityLogFilter, page, pageSize int) ([]*types.UserActivityLog, bool, error)
+	ExportAuditLogs(ctx context.Context, filter types.UserActivityLogFilter) ([]*types.UserActivityLog, error)
+	VerifyAuditChain(ctx context.Context) (*AuditChainReport, error)
+	Checkpoint(ctx context.Context) (*types.AuditCheckpoint, error)
+	StartCheckpoints(ctx context.Context, interval time.Duration)
+}
+
+type auditService struct {
+	repo repositories.AuditLogRepository
+
+	// Entries are appended to the hash chain one at a time
+	mu         sync.Mutex
+	head       auditChainHead
+	headLoaded bool
+}
+
+func NewAuditService(repo repositories.AuditLogRepository) AuditService {
+	return &auditService{
+		repo: repo,
+	}
+}
+
+// Record stores an audit log entry that is kept for the configured retention.
+// userID is uuid.Nil when the action cannot be tied to an account, e.g. a login with an unknown email.
+// Logins from an IP address or device the superuser never logged in from before are flagged
+// with new_ip and new_device in the metadata. Every entry is appended to the tamper-evident hash chain.
+func (s *auditService) Record(ctx context.Context, userID uuid.UUID, action, ipAddress, userAgent string, metadata map[string]interface{}) error {
+	// MongoDB keeps milliseconds, truncating here keeps both repositories ordering entries the same way
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	entry := &types.UserActivityLog{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Action:    action,
+		Timestamp: now,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Metadata:  metadata,
+	}
+	if configs.AuditRetention > 0 {
+		entry.ExpiresAt = now.Add(configs.AuditRetention)
+	}
+
+	if action == AuditLogin && userID != uuid.Nil {
+		if err := s.flagUnusualLogin(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(ctx, entry)
+}
+
+// AuditEmailHash returns a keyed hash of an email address for audit metadata. Attempts with the same address
+// can be matched up without the log, or an export of it, revealing which addresses were tried.
+func AuditEmailHash(email string) string {
+	return auditMAC("audit-email", strings.ToLower(strings.TrimSpace(email)))
+}
+
+// flagUnusualLogin compares a login with the superuser's earlier logins. The first login ever is not flagged.
+func (s *auditService) flagUnusualLogin(ctx context.Context, entry *types.UserActivityLog) error {
+	previous := types.UserActivityLogFilter{UserID: entry.UserID, Action: AuditLogin}
+	if found, err := s.exists(ctx, previous); err != nil || !found {
+		return err
+	}
+
+	seenIP := previous
+	seenIP.IPAddress = entry.IPAddress
+	knownIP, err := s.exists(ctx, seenIP)
+	if err != nil {
+		return err
+	}
+	seenDevice := previous
+	seenDevice.UserAgent = entry.UserAgent
+	knownDevice, err := s.exists(ctx, seenDevice)
+	if err != nil {
+		return err
+	}
+
+	if knownIP && knownDevice {
+		return nil
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = map[string]interface{}{}
+	}
+	if !knownIP {
+		entry.Metadata["new_ip"] = true
+	}
+	if !knownDevice {
+		entry.Metadata["new_device"] = true
+	}
+	return nil
+}
+
+// exists reports whether any entry matches filter.
+func (s *auditService) exists(ctx context.Context, filter types.UserActivityLogFilter) (bool, error) {
+	entries, err := s.repo.ListAuditLogs(ctx, filter, 1, 0)
+	return len(entries) > 0, err
+}
+
+// ListUserActivity returns one page of a superuser's own activity, and of admin actions taken on their account,
+// recorded before the given time, newest first, and whether more pages follow.
+// Keeping before fixed while paging stops new entries from shifting the pages.
+func (s *auditService) ListUserActivity(ctx context.Context, userID uuid.UUID, before time.Time, page, pageSize int) ([]*types.UserActivityLog, bool, error) {
+	return s.ListAuditLogsPage(ctx, types.UserActivityLogFilter{Subject: userID, To: before}, page, pageSize)
+}
+
+// ListAuditLogsPage returns one page of audit log entries matching filter, newest first,
+// and whether more pages follow. Pages start at 1.
+func (s *auditService) ListAuditLogsPage(ctx context.Context, filter types.UserActivityLogFilter, page, pageSize int) ([]*types.UserActivityLog, bool, error) {
+	if page < 1 {
+		page = 1
+	}
+	skip := (page - 1) * pageSize
+
+	// Fetch one extra record to find out whether there is a next page
+	results, err := s.repo.ListAuditLogs(ctx, filter, int64(pageSize+1), int64(skip))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(results) > pageSize {
+		return results[:pageSize], true, nil
+	}
+	return results, false, nil
+}
+
+// ExportAuditLogs returns the newest entries matching filter, up to the configured export limit.
+func (s *auditService) ExportAuditLogs(ctx context.Context, filter types.UserActivityLogFilter) ([]*types.UserActivityLog, error) {
+	return s.repo.ListAuditLogs(ctx, filter, int64(configs.AuditExportLimit), 0)
+}
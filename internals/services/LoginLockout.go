@@ -0,0 +1,171 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for a wrong password and for an unknown email alike.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// AccountLockedError is returned while too many failed logins keep an account locked.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account locked until " + e.Until.UTC().Format(time.RFC3339)
+}
+
+// LoginError ties a failed login to the account it was attempted on, so it can be audited under that account.
+// Failures for unknown emails are returned without it. It unwraps to the underlying error.
+type LoginError struct {
+	UserID uuid.UUID
+	Err    error
+}
+
+func (e *LoginError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// lockoutDuration returns the cool-down of the next lockout. It doubles with every lockout
+// since the last successful login, up to the configured maximum.
+func lockoutDuration(previousLockouts int) time.Duration {
+	duration := configs.LockoutBaseDuration
+	for i := 0; i < previousLockouts && duration < configs.LockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	if configs.LockoutMaxDuration > 0 && duration > configs.LockoutMaxDuration {
+		duration = configs.LockoutMaxDuration
+	}
+	return duration
+}
+
+// unknownLoginCapacity bounds the emails the unknown login tracker remembers. Beyond it the email
+// with the oldest failure is forgotten first.
+const unknownLoginCapacity = 10000
+
+// unknownLoginSweepInterval is how often the tracker forgets emails that can no longer affect a lockout.
+const unknownLoginSweepInterval = time.Minute
+
+// unknownLoginTracker locks out emails that belong to no superuser exactly like real accounts,
+// so the login responses do not reveal whether an email is registered. Emails are kept in a list
+// ordered by their latest failure, which caps memory and lets the sweep stop at the first live entry.
+type unknownLoginTracker struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // *unknownLogin, latest failure first
+}
+
+type unknownLogin struct {
+	email       string
+	attempts    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// newUnknownLoginTracker creates a tracker that is swept every unknownLoginSweepInterval until ctx is done.
+func newUnknownLoginTracker(ctx context.Context) *unknownLoginTracker {
+	t := &unknownLoginTracker{entries: make(map[string]*list.Element), order: list.New()}
+	go func() {
+		ticker := time.NewTicker(unknownLoginSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				t.sweep(now)
+			}
+		}
+	}()
+	return t
+}
+
+// lockedUntil returns when the lockout of an email ends, or the zero time if it is not locked.
+func (t *unknownLoginTracker) lockedUntil(email string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[strings.ToLower(email)]; ok {
+		if entry := element.Value.(*unknownLogin); now.Before(entry.lockedUntil) {
+			return entry.lockedUntil
+		}
+	}
+	return time.Time{}
+}
+
+// recordFailure counts a failed login for an email and returns the end of the lockout it triggered, if any.
+func (t *unknownLoginTracker) recordFailure(email string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := strings.ToLower(email)
+	element, ok := t.entries[key]
+	if ok {
+		t.order.MoveToFront(element)
+	} else {
+		element = t.order.PushFront(&unknownLogin{email: key})
+		t.entries[key] = element
+		if t.order.Len() > unknownLoginCapacity {
+			t.remove(t.order.Back())
+		}
+	}
+	entry := element.Value.(*unknownLogin)
+	entry.attempts++
+	entry.lastFailure = now
+
+	if configs.LockoutThreshold <= 0 || entry.attempts < configs.LockoutThreshold {
+		return time.Time{}
+	}
+	entry.lockedUntil = now.Add(lockoutDuration(entry.lockouts))
+	entry.attempts = 0
+	entry.lockouts++
+	return entry.lockedUntil
+}
+
+// sweep forgets emails whose last failure is too old to affect a lockout, starting with the oldest.
+func (t *unknownLoginTracker) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for element := t.order.Back(); element != nil; element = t.order.Back() {
+		entry := element.Value.(*unknownLogin)
+		if now.Sub(entry.lastFailure) <= configs.LockoutMaxDuration || now.Before(entry.lockedUntil) {
+			return
+		}
+		t.remove(element)
+	}
+}
+
+// remove forgets the email held by element. Callers must hold the lock.
+func (t *unknownLoginTracker) remove(element *list.Element) {
+	t.order.Remove(element)
+	delete(t.entries, element.Value.(*unknownLogin).email)
+}
+
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// compareDummyPassword spends as long as a real password check, so unknown emails cannot be told apart by timing.
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
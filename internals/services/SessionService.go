@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/repositories"
+	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or has expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+type SessionService interface {
+	StartSession(ctx context.Context, superuser *types.SuperUserType, userAgent, ipAddress string) (*tokens.TokenPair, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*tokens.TokenPair, error)
+	ValidateSession(ctx context.Context, payload *tokens.Payload) error
+	RecordActivity(ctx context.Context, payload *tokens.Payload, userAgent, ipAddress string) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*types.SessionType, error)
+	EndSession(ctx context.Context, payload *tokens.Payload) error
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error
+}
+
+type sessionService struct {
+	sessionRepo   repositories.SessionRepository
+	refreshRepo   repositories.RefreshTokenRepository
+	superuserRepo repositories.SuperuserRepository
+	tokenManager  tokens.TokenManager
+
+	// lastActivity throttles activity writes to one per session per activity interval
+	activityMu   sync.Mutex
+	lastActivity map[uuid.UUID]time.Time
+}
+
+func NewSessionService(sessionRepo repositories.SessionRepository, refreshRepo repositories.RefreshTokenRepository, superuserRepo repositories.SuperuserRepository, tokenManager tokens.TokenManager) SessionService {
+	return &sessionService{
+		sessionRepo:   sessionRepo,
+		refreshRepo:   refreshRepo,
+		superuserRepo: superuserRepo,
+		tokenManager:  tokenManager,
+		lastActivity:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+// StartSession records a new login session and issues its access token and first refresh token.
+// The session ID doubles as the refresh token family ID.
+func (s *sessionService) StartSession(ctx context.Context, superuser *types.SuperUserType, userAgent, ipAddress string) (*tokens.TokenPair, error) {
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := s.issueTokens(ctx, superuser, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.sessionRepo.CreateSession(ctx, &types.SessionType{
+		ID:         sessionID,
+		UserID:     superuser.ID,
+		TokenID:    pair.AccessPayload.ID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  pair.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RefreshSession rotates a refresh token, returning a new access token and a new refresh token of the same family.
+// Replaying a token that was already rotated revokes the whole family, since either the client or an attacker
+// holds a stolen copy. Replays within the configured grace period, e.g. from concurrent HTMX requests,
+// only receive a new access token.
+func (s *sessionService) RefreshSession(ctx context.Context, refreshToken string) (*tokens.TokenPair, error) {
+	stored, err := s.refreshRepo.FindRefreshTokenByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	now := time.Now()
+	if stored.Revoked || !now.Before(stored.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// Archived superusers and revoked sessions, e.g. after a password reset, cannot be refreshed
+	session, err := s.sessionRepo.FindSessionByID(ctx, stored.FamilyID)
+	if err != nil || session.Revoked {
+		return nil, ErrRefreshTokenInvalid
+	}
+	superuser, err := s.superuserRepo.FindSuperuserByID(ctx, stored.UserID)
+	if err != nil || superuser.Archived {
+		if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	marked, err := s.refreshRepo.MarkRefreshTokenUsed(ctx, stored.ID, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return s.handleReplay(ctx, superuser, refreshToken, now)
+	}
+
+	pair, err := s.issueTokens(ctx, superuser, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.TouchSession(ctx, session.ID, pair.AccessPayload.ID, now, pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// handleReplay deals with a refresh token that was already rotated or revoked.
+func (s *sessionService) handleReplay(ctx context.Context, superuser *types.SuperUserType, refreshToken string, now time.Time) (*tokens.TokenPair, error) {
+	// Reload the token, it may have been rotated or revoked since it was first read
+	stored, err := s.refreshRepo.FindRefreshTokenByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil || stored.Revoked {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if now.Sub(time.Unix(stored.UsedAt, 0)) <= configs.RefreshReuseGrace {
+		accessToken, payload, err := s.generateAccessToken(superuser, stored.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		return &tokens.TokenPair{AccessToken: accessToken, AccessPayload: payload}, nil
+	}
+
+	// The session is revoked too, so access tokens already handed out for it stop working
+	logging.FromContext(ctx).Warn("Refresh token reuse detected, revoking session", "superuser_id", superuser.ID, "session_id", stored.FamilyID)
+	if err := s.revokeSession(ctx, stored.FamilyID); err != nil {
+		return nil, err
+	}
+	return nil, ErrRefreshTokenReused
+}
+
+// ValidateSession rejects access tokens that were denylisted, belong to a revoked or expired session,
+// or were issued to a superuser who has since been archived.
+func (s *sessionService) ValidateSession(ctx context.Context, payload *tokens.Payload) error {
+	denied, err := s.sessionRepo.IsTokenDenied(ctx, payload.ID)
+	if err != nil {
+		return err
+	}
+	if denied {
+		return errors.New("token has been revoked")
+	}
+
+	session, err := s.sessionRepo.FindSessionByID(ctx, payload.SessionID)
+	if err != nil || session.UserID != payload.UserID || time.Now().After(session.ExpiresAt) {
+		return errors.New("session is no longer valid")
+	}
+	if session.Revoked {
+		return errors.New("session has been revoked")
+	}
+
+	superuser, err := s.superuserRepo.FindSuperuserByID(ctx, payload.UserID)
+	if err != nil || superuser.Archived {
+		return errors.New("session is no longer valid")
+	}
+	return nil
+}
+
+// RecordActivity stores the client and time of an authenticated request on its session.
+// Writes are throttled so a session is updated at most once per configured activity interval.
+func (s *sessionService) RecordActivity(ctx context.Context, payload *tokens.Payload, userAgent, ipAddress string) error {
+	now := time.Now()
+
+	s.activityMu.Lock()
+	if last, ok := s.lastActivity[payload.SessionID]; ok && now.Sub(last) < configs.SessionActivityInterval {
+		s.activityMu.Unlock()
+		return nil
+	}
+	s.lastActivity[payload.SessionID] = now
+
+	// Forget sessions that have been idle for a whole interval so the map does not grow without bound
+	for sessionID, last := range s.lastActivity {
+		if now.Sub(last) >= configs.SessionActivityInterval {
+			delete(s.lastActivity, sessionID)
+		}
+	}
+	s.activityMu.Unlock()
+
+	return s.sessionRepo.UpdateSessionActivity(ctx, payload.SessionID, payload.ID, userAgent, ipAddress, now)
+}
+
+// ListSessions returns the active sessions of a superuser, most recently used first.
+func (s *sessionService) ListSessions(ctx context.Context, userID uuid.UUID) ([]*types.SessionType, error) {
+	return s.sessionRepo.ListUserSessions(ctx, userID)
+}
+
+// RevokeSession revokes one of the superuser's own sessions, e.g. a device they no longer use.
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.sessionRepo.FindSessionByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, sessionID)
+}
+
+// EndSession logs out the session of an access token. The token itself is denylisted until it expires
+// and the session is revoked so its refresh token cannot be used either.
+func (s *sessionService) EndSession(ctx context.Context, payload *tokens.Payload) error {
+	if err := s.sessionRepo.DenyToken(ctx, payload.ID, payload.ExpiredAt); err != nil {
+		return err
+	}
+	return s.revokeSession(ctx, payload.SessionID)
+}
+
+// RevokeUserSessions logs a superuser out everywhere, e.g. after a password reset or role change.
+func (s *sessionService) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.sessionRepo.RevokeUserSessions(ctx, userID, uuid.Nil); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// RevokeOtherSessions logs a superuser out of every session except the current one.
+func (s *sessionService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error {
+	return s.sessionRepo.RevokeUserSessions(ctx, userID, currentSessionID)
+}
+
+// revokeSession revokes a session and its refresh token family.
+func (s *sessionService) revokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeRefreshTokenFamily(ctx, sessionID)
+}
+
+// issueTokens generates an access token and stores a new refresh token for the given session.
+func (s *sessionService) issueTokens(ctx context.Context, superuser *types.SuperUserType, sessionID uuid.UUID) (*tokens.TokenPair, error) {
+	accessToken, payload, err := s.generateAccessToken(superuser, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(configs.TokenRefreshDuration)
+	err = s.refreshRepo.CreateRefreshToken(ctx, &types.RefreshTokenType{
+		FamilyID:  sessionID,
+		UserID:    superuser.ID,
+		TokenHash: tokenHash,
+		CreatedAt: now.Unix(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokens.TokenPair{
+		AccessToken:      accessToken,
+		AccessPayload:    payload,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// generateAccessToken creates a full access token for the superuser's session.
+func (s *sessionService) generateAccessToken(superuser *types.SuperUserType, sessionID uuid.UUID) (string, *tokens.Payload, error) {
+	return s.tokenManager.GenerateToken(tokens.Claims{
+		UserID:    superuser.ID,
+		SessionID: sessionID,
+		Username:  superuser.Email,
+		Role:      superuser.Role,
+		Purpose:   tokens.PurposeAccess,
+		Duration:  configs.TokenAccessDuration,
+	})
+}
@@ -2,41 +2,102 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
 	"github.com/lordofthemind/htmx_GO/internals/repositories"
 	"github.com/lordofthemind/htmx_GO/internals/types"
+	"github.com/lordofthemind/htmx_GO/pkgs/email"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+	"github.com/lordofthemind/htmx_GO/pkgs/passwords"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+	"github.com/lordofthemind/htmx_GO/pkgs/twofactor"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrEmailNotVerified  = errors.New("email address has not been verified")
+	ErrInvalidResetToken = errors.New("invalid reset token")
+	ErrManageSelf        = errors.New("you cannot change your own account")
+	ErrManageHigher      = errors.New("you cannot manage a superuser or grant a role with permissions you do not hold")
+)
+
 type SuperuserService interface {
 	RegisterSuperuser(ctx context.Context, username, email, password string) error
 	AuthenticateSuperuser(ctx context.Context, email, password string) (*types.SuperUserType, error)
-	UpdateProfile(ctx context.Context, userID uuid.UUID, username, password string) error
+	UpdateProfile(ctx context.Context, userID, sessionID uuid.UUID, username, password string) error
 	SendPasswordResetEmail(ctx context.Context, email string) error
-	ResetPassword(ctx context.Context, token, password string) error
-	Verify2FA(ctx context.Context, userID uuid.UUID, code string) error
+	ResetPassword(ctx context.Context, token, password string) (uuid.UUID, error)
+	ResolvePermissions(ctx context.Context, payload *tokens.Payload) (rbac.PermissionSet, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	Setup2FA(ctx context.Context, userID uuid.UUID) (*twofactor.Enrollment, error)
+	Pending2FA(ctx context.Context, userID uuid.UUID) (*twofactor.Enrollment, error)
+	Verify2FA(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	CompleteLogin2FA(ctx context.Context, userID uuid.UUID, code string) (*types.SuperUserType, error)
+	RecordLogin(ctx context.Context, userID uuid.UUID, ipAddress string) error
+	UnlockSuperuser(ctx context.Context, actorID, userID uuid.UUID) error
+	ListRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]types.RecoveryCode, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password string) ([]string, error)
 	GetFilePath(fileID string) (string, error)
 	GetRole(ctx context.Context, userID uuid.UUID) (string, error)
-	UpdateRole(ctx context.Context, userID uuid.UUID, role string) error
+	UpdateRole(ctx context.Context, actorID, userID uuid.UUID, role string) error
 	Enable2FA(ctx context.Context, userID uuid.UUID, isEnabled bool) error
-	BulkUpdateSuperusers(ctx context.Context, ids []uuid.UUID, updates map[string]interface{}) error
+	BulkUpdateSuperusers(ctx context.Context, actorID uuid.UUID, ids []uuid.UUID, updates map[string]interface{}) error
 	SearchSuperusers(ctx context.Context, searchQuery string) ([]*types.SuperUserType, error)
+	ListSuperusersPage(ctx context.Context, searchQuery string, page, pageSize int) ([]*types.SuperUserType, bool, error)
+	GetSuperuser(ctx context.Context, userID uuid.UUID) (*types.SuperUserType, error)
+	ArchiveSuperuser(ctx context.Context, actorID, userID uuid.UUID) error
+	RestoreSuperuser(ctx context.Context, actorID, userID uuid.UUID) error
+	DeleteSuperuser(ctx context.Context, actorID, userID uuid.UUID) error
+	ListRoles() []string
+	RolePermissions(role string) []rbac.Permission
 }
 
 type superuserService struct {
-	repo repositories.SuperuserRepository
+	repo          repositories.SuperuserRepository
+	totpManager   twofactor.TOTPManager
+	tokenManager  tokens.TokenManager
+	mailer        email.Mailer
+	mailTemplates *email.TemplateRenderer
+	policy        *rbac.Policy
+	sessions      SessionService
+	passwords     *passwords.Policy
+	unknownLogins *unknownLoginTracker
 }
 
-func NewSuperuserService(repo repositories.SuperuserRepository) SuperuserService {
-	return &superuserService{repo: repo}
+// NewSuperuserService creates the superuser service. Emails of unknown logins are swept until ctx is done.
+func NewSuperuserService(ctx context.Context, repo repositories.SuperuserRepository, totpManager twofactor.TOTPManager, tokenManager tokens.TokenManager, mailer email.Mailer, mailTemplates *email.TemplateRenderer, policy *rbac.Policy, sessions SessionService, passwordPolicy *passwords.Policy) SuperuserService {
+	return &superuserService{
+		repo:          repo,
+		totpManager:   totpManager,
+		tokenManager:  tokenManager,
+		mailer:        mailer,
+		mailTemplates: mailTemplates,
+		policy:        policy,
+		sessions:      sessions,
+		passwords:     passwordPolicy,
+		unknownLogins: newUnknownLoginTracker(ctx),
+	}
 }
 
 // RegisterSuperuser creates a new superuser with hashed password.
+// A password that breaks the password policy is reported as a *passwords.PolicyError.
 func (s *superuserService) RegisterSuperuser(ctx context.Context, username, email, password string) error {
+	if err := s.passwords.Validate(password, username, email); err != nil {
+		return err
+	}
+
 	// Check if the email already exists
 	_, err := s.repo.FindSuperuserByEmail(ctx, email)
 	if err == nil {
@@ -55,97 +116,501 @@ func (s *superuserService) RegisterSuperuser(ctx context.Context, username, emai
 		Username:  username,
 		Email:     email,
 		Password:  string(hashedPassword),
+		Role:      s.policy.DefaultRole(),
 		CreatedAt: time.Now().Unix(),
 		UpdatedAt: time.Now().Unix(),
 	}
 
-	return s.repo.CreateSuperuser(ctx, superuser)
+	if err := s.repo.CreateSuperuser(ctx, superuser); err != nil {
+		return err
+	}
+
+	// A failed email does not undo the registration, the superuser can request a new link
+	if err := s.sendVerificationEmail(ctx, superuser); err != nil {
+		logging.FromContext(ctx).Error("Failed to send verification email to new superuser", "superuser_id", superuser.ID, "error", err)
+	}
+	return nil
 }
 
-// AuthenticateSuperuser verifies a superuser's credentials.
+// AuthenticateSuperuser verifies a superuser's credentials. Repeated failures lock the account
+// for an increasing cool-down, reported as an *AccountLockedError. Failures for registered emails
+// are wrapped in a *LoginError naming the account.
 func (s *superuserService) AuthenticateSuperuser(ctx context.Context, email, password string) (*types.SuperUserType, error) {
+	now := time.Now()
 	superuser, err := s.repo.FindSuperuserByEmail(ctx, email)
 	if err != nil {
-		return nil, errors.New("superuser not found")
+		// Unknown emails get the same responses, timing and lockouts as real accounts
+		if until := s.unknownLogins.lockedUntil(email, now); !until.IsZero() {
+			return nil, &AccountLockedError{Until: until}
+		}
+		compareDummyPassword(password)
+		if until := s.unknownLogins.recordFailure(email, now); !until.IsZero() {
+			return nil, &AccountLockedError{Until: until}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if superuser.LockedUntil > now.Unix() {
+		return nil, &LoginError{UserID: superuser.ID, Err: &AccountLockedError{Until: time.Unix(superuser.LockedUntil, 0)}}
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(superuser.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, &LoginError{UserID: superuser.ID, Err: s.recordFailedLogin(ctx, superuser)}
+	}
+
+	if superuser.Archived {
+		return nil, &LoginError{UserID: superuser.ID, Err: ErrInvalidCredentials}
+	}
+
+	if configs.RequireEmailVerify && !superuser.EmailVerified {
+		return nil, &LoginError{UserID: superuser.ID, Err: ErrEmailNotVerified}
 	}
 
 	return superuser, nil
 }
 
+// recordFailedLogin counts a failed password or 2FA code and locks the account once the threshold is reached.
+func (s *superuserService) recordFailedLogin(ctx context.Context, superuser *types.SuperUserType) error {
+	attempts, err := s.repo.RecordFailedLogin(ctx, superuser.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to record failed login", "superuser_id", superuser.ID, "error", err)
+		return ErrInvalidCredentials
+	}
+	if configs.LockoutThreshold <= 0 || attempts < configs.LockoutThreshold {
+		return ErrInvalidCredentials
+	}
+
+	until := time.Now().Add(lockoutDuration(superuser.LockoutCount))
+	if err := s.repo.LockSuperuser(ctx, superuser.ID, until.Unix()); err != nil {
+		logging.FromContext(ctx).Error("Failed to lock superuser", "superuser_id", superuser.ID, "error", err)
+		return ErrInvalidCredentials
+	}
+	return &AccountLockedError{Until: until}
+}
+
+// lockoutError returns an *AccountLockedError while the superuser is locked out, nil otherwise.
+func lockoutError(superuser *types.SuperUserType) error {
+	if superuser.LockedUntil > time.Now().Unix() {
+		return &AccountLockedError{Until: time.Unix(superuser.LockedUntil, 0)}
+	}
+	return nil
+}
+
+// RecordLogin stores the time and IP of a completed login and clears the failed login counters.
+func (s *superuserService) RecordLogin(ctx context.Context, userID uuid.UUID, ipAddress string) error {
+	return s.repo.RecordLogin(ctx, userID, time.Now().Unix(), ipAddress)
+}
+
+// UnlockSuperuser lifts a lockout before its cool-down ends.
+func (s *superuserService) UnlockSuperuser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if err := s.authorizeManagement(ctx, actorID, []uuid.UUID{userID}, ""); err != nil {
+		return err
+	}
+	return s.repo.UnlockSuperuser(ctx, userID)
+}
+
+// VerifyEmail marks the email address in a signed verification token as verified.
+func (s *superuserService) VerifyEmail(ctx context.Context, token string) error {
+	payload, err := s.tokenManager.ValidateToken(token)
+	if err != nil || payload.Purpose != tokens.PurposeEmailVerify {
+		return errors.New("invalid verification link")
+	}
+
+	// The token is bound to the address it was sent to
+	superuser, err := s.repo.FindSuperuserByID(ctx, payload.UserID)
+	if err != nil || superuser.Email != payload.Username {
+		return errors.New("invalid verification link")
+	}
+
+	if superuser.EmailVerified {
+		return nil
+	}
+	return s.repo.MarkEmailVerified(ctx, superuser.ID)
+}
+
+// ResendVerificationEmail sends a new verification link, at most once per configured interval.
+// Unknown and already verified addresses and requests within the interval are silently ignored,
+// so callers cannot probe which accounts exist.
+func (s *superuserService) ResendVerificationEmail(ctx context.Context, emailAddress string) error {
+	superuser, err := s.repo.FindSuperuserByEmail(ctx, emailAddress)
+	if err != nil || superuser.EmailVerified {
+		return nil
+	}
+
+	if time.Since(time.Unix(superuser.VerifySentAt, 0)) < configs.VerifyResendDelay {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, superuser)
+}
+
+// sendVerificationEmail emails a signed verification link and records when it was sent.
+func (s *superuserService) sendVerificationEmail(ctx context.Context, superuser *types.SuperUserType) error {
+	token, _, err := s.tokenManager.GenerateToken(tokens.Claims{
+		UserID:   superuser.ID,
+		Username: superuser.Email,
+		Role:     superuser.Role,
+		Purpose:  tokens.PurposeEmailVerify,
+		Duration: configs.TokenVerifyDuration,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.mailTemplates.Render("verify_email", []string{superuser.Email}, "Verify your email address", map[string]interface{}{
+		"Username":   superuser.Username,
+		"VerifyLink": fmt.Sprintf("%s/superuser/verify-email/%s", configs.BaseURL, token),
+		"ExpiresIn":  configs.TokenVerifyDuration.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.repo.UpdateVerifySentAt(ctx, superuser.ID, time.Now().Unix())
+}
+
 // UpdateProfile updates the username and password of a superuser.
-func (s *superuserService) UpdateProfile(ctx context.Context, userID uuid.UUID, username, password string) error {
+// Changing the password logs out every session except the one making the change.
+// A password that breaks the password policy is reported as a *passwords.PolicyError.
+func (s *superuserService) UpdateProfile(ctx context.Context, userID, sessionID uuid.UUID, username, password string) error {
 	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
+	// Changes go to a copy, the repository may hand out the stored record and a rejected password must not
+	// leave the new username applied
+	changed := *superuser
 	if username != "" {
-		superuser.Username = username
+		changed.Username = username
 	}
 
 	if password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			return errors.New("failed to hash password")
+		if err := s.setPassword(&changed, password); err != nil {
+			return err
 		}
-		superuser.Password = string(hashedPassword)
 	}
 
-	superuser.UpdatedAt = time.Now().Unix()
-	return s.repo.UpdateSuperuser(ctx, superuser)
+	changed.UpdatedAt = time.Now().Unix()
+	if err := s.repo.UpdateSuperuser(ctx, &changed); err != nil {
+		return err
+	}
+
+	if password != "" {
+		return s.sessions.RevokeOtherSessions(ctx, userID, sessionID)
+	}
+	return nil
 }
 
-// SendPasswordResetEmail sends a reset token (placeholder functionality).
-func (s *superuserService) SendPasswordResetEmail(ctx context.Context, email string) error {
-	superuser, err := s.repo.FindSuperuserByEmail(ctx, email)
+// SendPasswordResetEmail emails a single-use, expiring reset link to the superuser.
+// Unknown email addresses are ignored so callers cannot probe which accounts exist.
+func (s *superuserService) SendPasswordResetEmail(ctx context.Context, emailAddress string) error {
+	superuser, err := s.repo.FindSuperuserByEmail(ctx, emailAddress)
+	if err != nil {
+		logging.FromContext(ctx).Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	resetToken, tokenHash, err := generateOpaqueToken()
 	if err != nil {
 		return err
 	}
 
-	// Generate and send a reset token (placeholder logic)
-	resetToken := "generated-reset-token"
-	fmt.Printf("Sending password reset token to %s: %s\n", superuser.Email, resetToken)
+	// Only the hash is stored, the plain token only exists in the email
+	expiresAt := time.Now().Add(configs.TokenResetDuration)
+	if err := s.repo.UpdateResetToken(ctx, superuser.ID, tokenHash, expiresAt.Unix()); err != nil {
+		return err
+	}
 
-	return s.repo.UpdateResetToken(ctx, superuser.ID, resetToken)
+	msg, err := s.mailTemplates.Render("password_reset", []string{superuser.Email}, "Reset your password", map[string]interface{}{
+		"Username":  superuser.Username,
+		"ResetLink": fmt.Sprintf("%s/superuser/password-reset/%s", configs.BaseURL, resetToken),
+		"ExpiresIn": configs.TokenResetDuration.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
 }
 
-// ResetPassword resets the password of a superuser using a token.
-func (s *superuserService) ResetPassword(ctx context.Context, token, password string) error {
-	superuser, err := s.repo.FindSuperuserByResetToken(ctx, token)
-	if err != nil {
-		return errors.New("invalid reset token")
+// ResetPassword resets the password of a superuser using a token, signs out all existing sessions
+// and returns the superuser's ID.
+// A password that breaks the password policy is reported as a *passwords.PolicyError and leaves the token usable.
+func (s *superuserService) ResetPassword(ctx context.Context, token, password string) (uuid.UUID, error) {
+	if token == "" {
+		return uuid.Nil, ErrInvalidResetToken
+	}
+
+	// The password is checked before the token is spent, so a rejected password can be retried with the same link
+	tokenHash := hashOpaqueToken(token)
+	owner, err := s.repo.FindSuperuserByResetToken(ctx, tokenHash)
+	if err != nil || owner.ResetTokenExpiry <= time.Now().Unix() {
+		return uuid.Nil, ErrInvalidResetToken
+	}
+	changed := *owner // nothing is stored until the token has been consumed
+	if err := s.setPassword(&changed, password); err != nil {
+		return uuid.Nil, err
+	}
+
+	superuser, err := s.repo.ConsumeResetToken(ctx, tokenHash)
+	if err != nil || superuser.ID != owner.ID {
+		return uuid.Nil, ErrInvalidResetToken
+	}
+	superuser.Password = changed.Password
+	superuser.PasswordHistory = changed.PasswordHistory
+	superuser.UpdatedAt = time.Now().Unix()
+
+	if err := s.repo.UpdateSuperuser(ctx, superuser); err != nil {
+		return uuid.Nil, err
+	}
+	// Proving control of the email address also lifts a lockout
+	if err := s.repo.UnlockSuperuser(ctx, superuser.ID); err != nil {
+		return uuid.Nil, err
+	}
+	return superuser.ID, s.sessions.RevokeUserSessions(ctx, superuser.ID)
+}
+
+// setPassword checks a new password against the policy and the password history, then hashes it
+// and moves the old hash into the history. The caller stores the superuser.
+func (s *superuserService) setPassword(superuser *types.SuperUserType, password string) error {
+	if err := s.passwords.Validate(password, superuser.Username, superuser.Email); err != nil {
+		return err
+	}
+
+	// The current password counts as the first entry of the history
+	history := s.passwords.History()
+	previous := append([]string{superuser.Password}, superuser.PasswordHistory...)
+	if len(previous) > history {
+		previous = previous[:history]
+	}
+	for _, hash := range previous {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return s.passwords.HistoryViolation()
+		}
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return errors.New("failed to hash password")
 	}
-
 	superuser.Password = string(hashedPassword)
-	superuser.UpdatedAt = time.Now().Unix()
+	if history > 1 {
+		superuser.PasswordHistory = previous[:min(len(previous), history-1)]
+	} else {
+		superuser.PasswordHistory = nil
+	}
+	return nil
+}
+
+// ResolvePermissions returns the permissions granted by the role and permission groups of a token's superuser.
+func (s *superuserService) ResolvePermissions(ctx context.Context, payload *tokens.Payload) (rbac.PermissionSet, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, payload.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.policy.PermissionsFor(superuser.Role, superuser.PermissionGroups), nil
+}
+
+// generateOpaqueToken returns a random URL-safe token and the hash stored for it.
+// It is used for password reset and refresh tokens, which only the client ever sees in plain form.
+func generateOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", errors.New("failed to generate token")
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashOpaqueToken(token), nil
+}
 
-	return s.repo.UpdateSuperuser(ctx, superuser)
+// hashOpaqueToken returns the hex encoded SHA-256 hash of an opaque token.
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-// Verify2FA verifies the 2FA code (placeholder logic).
-func (s *superuserService) Verify2FA(ctx context.Context, userID uuid.UUID, code string) error {
+// Setup2FA generates a new pending TOTP secret for a superuser.
+// 2FA is not enabled until the first code generated from the secret is verified.
+func (s *superuserService) Setup2FA(ctx context.Context, userID uuid.UUID) (*twofactor.Enrollment, error) {
 	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
 	if err != nil {
+		return nil, err
+	}
+
+	if superuser.Is2FAEnabled {
+		return nil, errors.New("2FA is already enabled")
+	}
+
+	enrollment, err := s.totpManager.GenerateEnrollment(superuser.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateTOTPSecret(ctx, superuser.ID, enrollment.Secret); err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// Pending2FA returns the enrollment started by Setup2FA that has not been verified yet, or nil when there is none.
+func (s *superuserService) Pending2FA(ctx context.Context, userID uuid.UUID) (*twofactor.Enrollment, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if superuser.Is2FAEnabled {
+		return nil, errors.New("2FA is already enabled")
+	}
+	if superuser.TOTPSecret == "" {
+		return nil, nil
+	}
+	return s.totpManager.EnrollmentFor(superuser.Email, superuser.TOTPSecret)
+}
+
+// Verify2FA verifies a 2FA code. On the first successful verification 2FA is enabled
+// and a fresh set of recovery codes is returned; they are never retrievable again.
+func (s *superuserService) Verify2FA(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Already enrolled superusers may also prove possession with a recovery code.
+	// Wrong codes count towards the login lockout, the code must not be guessable from a session.
+	if superuser.Is2FAEnabled {
+		if err := lockoutError(superuser); err != nil {
+			return nil, err
+		}
+		if err := s.verifySecondFactor(ctx, superuser, code); err != nil {
+			return nil, s.recordFailedLogin(ctx, superuser)
+		}
+		return nil, nil
+	}
+
+	if err := s.verifyTOTP(ctx, superuser, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Enable2FA(ctx, superuser.ID, true); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, superuser)
+}
+
+// CompleteLogin2FA verifies the second factor of a login for a superuser whose password was already checked.
+func (s *superuserService) CompleteLogin2FA(ctx context.Context, userID uuid.UUID, code string) (*types.SuperUserType, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !superuser.Is2FAEnabled {
+		return nil, errors.New("2FA is not enabled")
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	if err := lockoutError(superuser); err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, superuser, code); err != nil {
+		return nil, s.recordFailedLogin(ctx, superuser)
+	}
+
+	return superuser, nil
+}
+
+// ListRecoveryCodes returns the recovery codes of a superuser with their usage state. Each code is labelled
+// with its position in the list the superuser was given, which reveals nothing about the code itself.
+func (s *superuserService) ListRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]types.RecoveryCode, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !superuser.Is2FAEnabled {
+		return nil, errors.New("2FA is not enabled")
+	}
+
+	codes := append([]types.RecoveryCode(nil), superuser.RecoveryCodes...)
+	for i := range codes {
+		codes[i].Hint = fmt.Sprintf("code %d of %d", i+1, len(codes))
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after re-checking the superuser's password.
+func (s *superuserService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password string) ([]string, error) {
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A stolen session must not be able to guess the password freely, failures count towards the lockout
+	if err := lockoutError(superuser); err != nil {
+		return nil, err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(superuser.Password), []byte(password))
+	if err != nil {
+		return nil, s.recordFailedLogin(ctx, superuser)
+	}
+
+	if !superuser.Is2FAEnabled {
+		return nil, errors.New("2FA is not enabled")
+	}
+
+	return s.replaceRecoveryCodes(ctx, superuser)
+}
+
+// replaceRecoveryCodes generates new recovery codes, stores their hashes and returns the plain codes.
+func (s *superuserService) replaceRecoveryCodes(ctx context.Context, superuser *types.SuperUserType) ([]string, error) {
+	codes, err := twofactor.GenerateRecoveryCodes(configs.TOTPRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]types.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		recoveryCodes = append(recoveryCodes, types.RecoveryCode{
+			Hash: twofactor.HashRecoveryCode(code),
+		})
+	}
+
+	if err := s.repo.UpdateRecoveryCodes(ctx, superuser.ID, recoveryCodes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+func (s *superuserService) verifySecondFactor(ctx context.Context, superuser *types.SuperUserType, code string) error {
+	err := s.verifyTOTP(ctx, superuser, code)
+	if err == nil || !errors.Is(err, twofactor.ErrInvalidCode) {
 		return err
 	}
 
-	// Verify 2FA code (placeholder)
-	if code != "expected-2fa-code" {
-		return errors.New("invalid 2FA code")
+	return s.repo.ConsumeRecoveryCode(ctx, superuser.ID, twofactor.HashRecoveryCode(code))
+}
+
+// verifyTOTP checks a TOTP code against the superuser's secret and records the used time step.
+func (s *superuserService) verifyTOTP(ctx context.Context, superuser *types.SuperUserType, code string) error {
+	if superuser.TOTPSecret == "" {
+		return errors.New("2FA has not been set up")
 	}
 
-	superuser.Is2FAEnabled = true
-	return s.repo.UpdateSuperuser(ctx, superuser)
+	step, err := s.totpManager.ValidateCode(superuser.TOTPSecret, code, superuser.TOTPLastUsedStep)
+	if err != nil {
+		return err
+	}
+
+	// Persist the matched step so the same code cannot be used again
+	return s.repo.UpdateTOTPLastUsedStep(ctx, superuser.ID, step)
 }
 
 // Enable2FA enables or disables 2FA for a superuser.
@@ -153,10 +618,17 @@ func (s *superuserService) Enable2FA(ctx context.Context, userID uuid.UUID, isEn
 	return s.repo.Enable2FA(ctx, userID, isEnabled)
 }
 
-// GetFilePath returns the file path for a given file ID (placeholder functionality).
+// GetFilePath returns the path of an uploaded file. Only plain file names inside the upload directory resolve.
 func (s *superuserService) GetFilePath(fileID string) (string, error) {
-	// Placeholder logic for file path
-	return fmt.Sprintf("./uploads/%s", fileID), nil
+	name := filepath.Base(fileID)
+	if name != fileID || name == "." || name == ".." {
+		return "", errors.New("invalid file name")
+	}
+	path := filepath.Join("./uploads", name)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", errors.New("file not found")
+	}
+	return path, nil
 }
 
 // GetRole retrieves the role of a superuser by their ID.
@@ -164,17 +636,158 @@ func (s *superuserService) GetRole(ctx context.Context, userID uuid.UUID) (strin
 	return s.repo.GetRoleByID(ctx, userID)
 }
 
-// UpdateRole updates the role of a superuser by their ID.
-func (s *superuserService) UpdateRole(ctx context.Context, userID uuid.UUID, role string) error {
-	return s.repo.UpdateSuperuserRole(ctx, userID, role)
+// UpdateRole updates the role of a superuser by their ID on behalf of actorID.
+// The actor must hold every permission of the new role and may not change their own role.
+func (s *superuserService) UpdateRole(ctx context.Context, actorID, userID uuid.UUID, role string) error {
+	if !s.policy.HasRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	if err := s.authorizeManagement(ctx, actorID, []uuid.UUID{userID}, role); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateSuperuserRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	// Tokens carry the role they were issued with, so the superuser has to log in again
+	return s.sessions.RevokeUserSessions(ctx, userID)
 }
 
-// BulkUpdateSuperusers updates multiple superusers at once.
-func (s *superuserService) BulkUpdateSuperusers(ctx context.Context, ids []uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.BulkUpdateSuperusers(ctx, ids, updates)
+// BulkUpdateSuperusers updates multiple superusers at once on behalf of actorID.
+// Every selected superuser must be manageable by the actor, otherwise nothing is changed.
+func (s *superuserService) BulkUpdateSuperusers(ctx context.Context, actorID uuid.UUID, ids []uuid.UUID, updates map[string]interface{}) error {
+	role, roleChanged := updates["role"].(string)
+	if roleChanged && !s.policy.HasRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	if err := s.authorizeManagement(ctx, actorID, ids, role); err != nil {
+		return err
+	}
+	if err := s.repo.BulkUpdateSuperusers(ctx, ids, updates); err != nil {
+		return err
+	}
+
+	if roleChanged {
+		for _, id := range ids {
+			if err := s.sessions.RevokeUserSessions(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // SearchSuperusers allows searching for superusers based on partial matches of full name, username, or email.
 func (s *superuserService) SearchSuperusers(ctx context.Context, searchQuery string) ([]*types.SuperUserType, error) {
 	return s.repo.SearchSuperusers(ctx, searchQuery)
 }
+
+// ListSuperusersPage returns one page of superusers, optionally filtered by a search query,
+// and whether more pages follow. Pages start at 1.
+func (s *superuserService) ListSuperusersPage(ctx context.Context, searchQuery string, page, pageSize int) ([]*types.SuperUserType, bool, error) {
+	if page < 1 {
+		page = 1
+	}
+	skip := (page - 1) * pageSize
+
+	// Search results are not paginated by the repository, slice them here
+	if searchQuery != "" {
+		results, err := s.repo.SearchSuperusers(ctx, searchQuery)
+		if err != nil {
+			return nil, false, err
+		}
+		if skip >= len(results) {
+			return nil, false, nil
+		}
+		end := skip + pageSize
+		if end >= len(results) {
+			return results[skip:], false, nil
+		}
+		return results[skip:end], true, nil
+	}
+
+	// Fetch one extra record to find out whether there is a next page
+	results, err := s.repo.ListSuperusers(ctx, int64(pageSize+1), int64(skip))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(results) > pageSize {
+		return results[:pageSize], true, nil
+	}
+	return results, false, nil
+}
+
+// GetSuperuser returns a superuser by ID.
+func (s *superuserService) GetSuperuser(ctx context.Context, userID uuid.UUID) (*types.SuperUserType, error) {
+	return s.repo.FindSuperuserByID(ctx, userID)
+}
+
+// ArchiveSuperuser soft deletes a superuser, blocking logins while keeping the record.
+func (s *superuserService) ArchiveSuperuser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if err := s.authorizeManagement(ctx, actorID, []uuid.UUID{userID}, ""); err != nil {
+		return err
+	}
+	return s.repo.SoftDeleteSuperuser(ctx, userID)
+}
+
+// RestoreSuperuser reactivates an archived superuser.
+func (s *superuserService) RestoreSuperuser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if err := s.authorizeManagement(ctx, actorID, []uuid.UUID{userID}, ""); err != nil {
+		return err
+	}
+	return s.repo.RestoreSuperuser(ctx, userID)
+}
+
+// DeleteSuperuser permanently removes a superuser. Only archived superusers can be deleted.
+func (s *superuserService) DeleteSuperuser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if err := s.authorizeManagement(ctx, actorID, []uuid.UUID{userID}, ""); err != nil {
+		return err
+	}
+	superuser, err := s.repo.FindSuperuserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !superuser.Archived {
+		return errors.New("superuser must be archived before it can be deleted")
+	}
+	return s.repo.DeleteSuperuserByID(ctx, userID)
+}
+
+// authorizeManagement checks that actorID may manage every target and, when role is set, grant that role.
+// Nobody manages their own account, targets may not hold permissions the actor lacks, and a role may only
+// be granted by someone who already holds all of its permissions.
+func (s *superuserService) authorizeManagement(ctx context.Context, actorID uuid.UUID, targetIDs []uuid.UUID, role string) error {
+	actor, err := s.repo.FindSuperuserByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	actorPermissions := s.policy.PermissionsFor(actor.Role, actor.PermissionGroups)
+
+	if role != "" && !actorPermissions.Covers(s.policy.PermissionsFor(role, nil)) {
+		return ErrManageHigher
+	}
+	for _, targetID := range targetIDs {
+		if targetID == actorID {
+			return ErrManageSelf
+		}
+		target, err := s.repo.FindSuperuserByID(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		if !actorPermissions.Covers(s.policy.PermissionsFor(target.Role, target.PermissionGroups)) {
+			return ErrManageHigher
+		}
+	}
+	return nil
+}
+
+// ListRoles returns the names of all roles defined in the RBAC policy.
+func (s *superuserService) ListRoles() []string {
+	return s.policy.Roles()
+}
+
+// RolePermissions returns the permissions granted by a role.
+func (s *superuserService) RolePermissions(role string) []rbac.Permission {
+	return s.policy.RolePermissions(role)
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyType is a long-lived personal API key of a superuser. The key itself is only shown once,
+// it is found again by its prefix and checked against the stored hash.
+type APIKeyType struct {
+	ID           uuid.UUID `bson:"_id" json:"id"`
+	UserID       uuid.UUID `bson:"user_id" json:"user_id"`             // Superuser who owns the key
+	Name         string    `bson:"name" json:"name"`                   // Label given when the key was created
+	Prefix       string    `bson:"prefix" json:"prefix"`               // Public part of the key used for lookup
+	KeyHash      string    `bson:"key_hash" json:"-"`                  // SHA-256 hash of the full key
+	Scopes       []string  `bson:"scopes" json:"scopes"`               // RBAC permissions the key may use
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`       // When the key was created
+	LastUsedAt   time.Time `bson:"last_used_at" json:"last_used_at"`   // Zero until the key is first used
+	LastUsedIP   string    `bson:"last_used_ip" json:"last_used_ip"`   // Client IP of the latest request made with the key
+	RequestCount int64     `bson:"request_count" json:"request_count"` // Number of authenticated requests made with the key
+	Revoked      bool      `bson:"revoked" json:"revoked"`
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditCheckpoint is a signed snapshot of the audit log hash chain. It pins the hash of the newest entry
+// at the time it was taken, so removing or rewriting entries up to that point cannot go unnoticed.
+// Checkpoints form a chain of their own, each one signing the MAC of the one before it, so none of them
+// can be removed either.
+type AuditCheckpoint struct {
+	ID             uuid.UUID `bson:"_id" json:"id"`
+	Number         int64     `bson:"number" json:"number"`                     // Position in the checkpoint chain, starting at 1
+	Sequence       int64     `bson:"sequence" json:"sequence"`                 // Sequence of the newest entry when the checkpoint was taken
+	Hash           string    `bson:"hash" json:"hash"`                         // Hash of that entry
+	EntryExpiresAt time.Time `bson:"entry_expires_at" json:"entry_expires_at"` // When that entry is due to be removed by the retention, zero if never
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	Resumed        bool      `bson:"resumed" json:"resumed"`   // First checkpoint after the server started, a longer gap before it is expected
+	PrevMAC        string    `bson:"prev_mac" json:"prev_mac"` // MAC of the previous checkpoint, empty for the first one
+	MAC            string    `bson:"mac" json:"mac"`           // HMAC of the fields above with the server's audit key
+}
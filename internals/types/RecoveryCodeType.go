@@ -0,0 +1,8 @@
+package types
+
+// RecoveryCode is a single-use 2FA backup code. Only the hash of the code is stored.
+type RecoveryCode struct {
+	Hash   string `bson:"hash" json:"-"`           // SHA-256 hash of the normalized code
+	UsedAt int64  `bson:"used_at" json:"used_at"`  // Unix time the code was consumed, 0 if unused
+	Hint   string `bson:"-" json:"hint,omitempty"` // Position label such as "code 3 of 10", never part of the code
+}
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenType is a stored refresh token. Each login starts a new family and every
+// rotation adds a token to it, so replaying a used token can revoke the whole family.
+type RefreshTokenType struct {
+	ID        uuid.UUID `bson:"_id,omitempty" json:"id"`
+	FamilyID  uuid.UUID `bson:"family_id" json:"family_id"` // Shared by every token rotated from the same login
+	UserID    uuid.UUID `bson:"user_id" json:"user_id"`     // Superuser the token was issued to
+	TokenHash string    `bson:"token_hash" json:"-"`        // SHA-256 hash of the token sent to the client
+	CreatedAt int64     `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`     // A date, so the TTL index removes expired tokens
+	UsedAt    int64     `bson:"used_at" json:"used_at,omitempty"` // Unix time the token was rotated, 0 if unused
+	Revoked   bool      `bson:"revoked" json:"revoked"`
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionType is a login session. Its ID is shared by the refresh token family and
+// carried as the session ID of every access token issued for the login.
+type SessionType struct {
+	ID         uuid.UUID `bson:"_id" json:"id"`
+	UserID     uuid.UUID `bson:"user_id" json:"user_id"`           // Superuser who logged in
+	TokenID    uuid.UUID `bson:"token_id" json:"token_id"`         // ID of the latest access token used with the session
+	UserAgent  string    `bson:"user_agent" json:"user_agent"`     // Browser or client the session was last used from
+	IPAddress  string    `bson:"ip_address" json:"ip_address"`     // Client IP the session was last used from
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`     // When the login happened
+	LastSeenAt time.Time `bson:"last_seen_at" json:"last_seen_at"` // Last authenticated request, recorded at most once per activity interval
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`     // Sessions are removed once their refresh token can no longer be used
+	Revoked    bool      `bson:"revoked" json:"revoked"`
+}
+
+// RevokedTokenType is a denylisted token ID. Entries only need to be kept until the token would have expired anyway.
+type RevokedTokenType struct {
+	ID        uuid.UUID `bson:"_id" json:"id"`                // Token ID (jti)
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"` // Expiry of the revoked token
+}
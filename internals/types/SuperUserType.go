@@ -6,17 +6,30 @@ import (
 
 // Superuser represents a user with administrative privileges.
 type SuperUserType struct {
-	ID               uuid.UUID `bson:"_id,omitempty" json:"id"`
-	FullName         string    `bson:"full_name" json:"full_name" validate:"required,min=3,max=32"`
-	Username         string    `bson:"username" json:"username" validate:"required,min=3,max=32"`
-	Email            string    `bson:"email" json:"email" validate:"required,email"`
-	Password         string    `bson:"password" json:"password" validate:"required,min=6"`
-	Role             string    `bson:"role" json:"role" validate:"required"`
-	CreatedAt        int64     `bson:"created_at" json:"created_at"`
-	UpdatedAt        int64     `bson:"updated_at" json:"updated_at"`
-	Is2FAEnabled     bool      `bson:"is_2fa_enabled" json:"is_2fa_enabled"`
-	ResetToken       string    `bson:"reset_token" json:"reset_token"`
-	PermissionGroups []string  `bson:"permission_groups" json:"permission_groups"`
+	ID               uuid.UUID      `bson:"_id,omitempty" json:"id"`
+	FullName         string         `bson:"full_name" json:"full_name" validate:"required,min=3,max=32"`
+	Username         string         `bson:"username" json:"username" validate:"required,min=3,max=32"`
+	Email            string         `bson:"email" json:"email" validate:"required,email"`
+	Password         string         `bson:"password" json:"-" validate:"required,min=6"`
+	PasswordHistory  []string       `bson:"password_history" json:"-"` // bcrypt hashes of previous passwords, newest first
+	Role             string         `bson:"role" json:"role" validate:"required"`
+	CreatedAt        int64          `bson:"created_at" json:"created_at"`
+	UpdatedAt        int64          `bson:"updated_at" json:"updated_at"`
+	Is2FAEnabled     bool           `bson:"is_2fa_enabled" json:"is_2fa_enabled"`
+	ResetToken       string         `bson:"reset_token" json:"-"` // SHA-256 hash of the emailed token
+	ResetTokenExpiry int64          `bson:"reset_token_expiry" json:"-"`
+	PermissionGroups []string       `bson:"permission_groups" json:"permission_groups"`
+	TOTPSecret       string         `bson:"totp_secret" json:"-"`
+	TOTPLastUsedStep int64          `bson:"totp_last_used_step" json:"-"`
+	RecoveryCodes    []RecoveryCode `bson:"recovery_codes" json:"-"`
+	EmailVerified    bool           `bson:"email_verified" json:"email_verified"`
+	VerifySentAt     int64          `bson:"verify_sent_at" json:"-"` // last time a verification email was sent
+	Archived         bool           `bson:"archived" json:"archived"`
+	FailedAttempts   int            `bson:"failed_attempts" json:"failed_attempts"` // failed logins since the last success or lockout
+	LockoutCount     int            `bson:"lockout_count" json:"lockout_count"`     // lockouts since the last success, each one doubles the cool-down
+	LockedUntil      int64          `bson:"locked_until" json:"locked_until"`       // logins are refused until this time
+	LastLoginAt      int64          `bson:"last_login_at" json:"last_login_at"`
+	LastLoginIP      string         `bson:"last_login_ip" json:"last_login_ip"`
 }
 
 // // Superuser represents a user with administrative privileges.
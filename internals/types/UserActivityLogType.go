@@ -8,10 +8,27 @@ import (
 
 // UserActivityLog represents a log entry for a user's activity
 type UserActivityLog struct {
-	ID        uuid.UUID              `bson:"_id" json:"id"`                                // Unique identifier for the log entry
-	UserID    uuid.UUID              `bson:"user_id" json:"user_id"`                       // ID of the user who performed the activity
-	Action    string                 `bson:"action" json:"action"`                         // Description of the activity performed
-	Timestamp time.Time              `bson:"timestamp" json:"timestamp"`                   // When the activity occurred
-	IPAddress string                 `bson:"ip_address,omitempty" json:"ip_address"`       // Optional: User's IP address
-	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"` // Optional: Additional metadata
+	ID        uuid.UUID              `bson:"_id" json:"id"`                                  // Unique identifier for the log entry
+	UserID    uuid.UUID              `bson:"user_id" json:"user_id"`                         // ID of the user who performed the activity
+	Action    string                 `bson:"action" json:"action"`                           // Description of the activity performed
+	Timestamp time.Time              `bson:"timestamp" json:"timestamp"`                     // When the activity occurred
+	IPAddress string                 `bson:"ip_address,omitempty" json:"ip_address"`         // Optional: User's IP address
+	UserAgent string                 `bson:"user_agent,omitempty" json:"user_agent"`         // Optional: Browser or client used
+	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`   // Optional: Additional metadata
+	ExpiresAt time.Time              `bson:"expires_at" json:"-"`                            // Removed by the retention TTL after this time
+	Sequence  int64                  `bson:"sequence,omitempty" json:"sequence,omitempty"`   // Position in the hash chain, starting at 1
+	PrevHash  string                 `bson:"prev_hash,omitempty" json:"prev_hash,omitempty"` // Hash of the previous entry in the chain
+	Hash      string                 `bson:"hash,omitempty" json:"hash,omitempty"`           // SHA-256 of this entry and PrevHash
+	MAC       string                 `bson:"mac,omitempty" json:"mac,omitempty"`             // HMAC of Hash with the server's audit key
+}
+
+// UserActivityLogFilter narrows an audit log listing. Zero values match every entry.
+type UserActivityLogFilter struct {
+	UserID    uuid.UUID
+	Subject   uuid.UUID // entries by this superuser or naming them in the target_ids metadata, e.g. an admin changing their role
+	Action    string
+	IPAddress string
+	UserAgent string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
 }
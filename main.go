@@ -1,7 +1,21 @@
 package main
 
-import "github.com/lordofthemind/htmx_GO/cmd/server"
+import (
+	"os"
+
+	"github.com/lordofthemind/htmx_GO/cmd/admin"
+	"github.com/lordofthemind/htmx_GO/cmd/audit"
+	"github.com/lordofthemind/htmx_GO/cmd/server"
+)
 
 func main() {
+	// "htmx_GO audit verify" checks the audit log instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(audit.RunAudit(os.Args[2:]))
+	}
+	// "htmx_GO admin promote <email>" gives a superuser a role, e.g. the first administrator
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(admin.RunAdmin(os.Args[2:]))
+	}
 	server.RunServer()
 }
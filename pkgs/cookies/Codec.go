@@ -0,0 +1,143 @@
+package cookies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+)
+
+// ErrInvalidCookie is returned when a cookie value was not produced by the codec, or was tampered with.
+var ErrInvalidCookie = errors.New("invalid cookie value")
+
+// Codec turns cookie values into what is stored in the browser and back.
+// The cookie name is bound into the result, so a value cannot be moved to another cookie.
+type Codec interface {
+	Encode(name, value string) (string, error)
+	Decode(name, value string) (string, error)
+}
+
+// NewCodec creates the codec selected by cookies.codec.
+func NewCodec() (Codec, error) {
+	switch configs.CookieCodec {
+	case "", "none":
+		return PlainCodec{}, nil
+	case "signed":
+		return NewSignedCodec([]byte(configs.CookieKey)), nil
+	case "encrypted":
+		return NewEncryptedCodec([]byte(configs.CookieKey))
+	}
+	return nil, fmt.Errorf("unsupported cookie codec %q", configs.CookieCodec)
+}
+
+// PlainCodec stores values as they are.
+type PlainCodec struct{}
+
+// Encode returns the value unchanged.
+func (PlainCodec) Encode(name, value string) (string, error) { return value, nil }
+
+// Decode returns the value unchanged.
+func (PlainCodec) Decode(name, value string) (string, error) { return value, nil }
+
+// SignedCodec appends an HMAC-SHA256 of the cookie name and value. Values stay readable but cannot be altered.
+type SignedCodec struct {
+	key []byte
+}
+
+// NewSignedCodec derives the signing key from secret.
+func NewSignedCodec(secret []byte) *SignedCodec {
+	return &SignedCodec{key: deriveKey(secret, "cookie-signing")}
+}
+
+// Encode returns base64(value).base64(mac).
+func (s *SignedCodec) Encode(name, value string) (string, error) {
+	return encode([]byte(value)) + "." + encode(s.mac(name, value)), nil
+}
+
+// Decode verifies the signature and returns the original value.
+func (s *SignedCodec) Decode(name, value string) (string, error) {
+	encodedValue, encodedMAC, found := strings.Cut(value, ".")
+	if !found {
+		return "", ErrInvalidCookie
+	}
+	raw, err := decode(encodedValue)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	mac, err := decode(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.mac(name, string(raw))) {
+		return "", ErrInvalidCookie
+	}
+	return string(raw), nil
+}
+
+func (s *SignedCodec) mac(name, value string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+// EncryptedCodec seals values with AES-256-GCM, using the cookie name as additional data.
+type EncryptedCodec struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptedCodec derives the encryption key from secret.
+func NewEncryptedCodec(secret []byte) (*EncryptedCodec, error) {
+	block, err := aes.NewCipher(deriveKey(secret, "cookie-encryption"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie cipher: %w", err)
+	}
+	return &EncryptedCodec{aead: aead}, nil
+}
+
+// Encode returns base64(nonce || ciphertext).
+func (e *EncryptedCodec) Encode(name, value string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate cookie nonce: %w", err)
+	}
+	return encode(e.aead.Seal(nonce, nonce, []byte(value), []byte(name))), nil
+}
+
+// Decode opens a value sealed by Encode for the same cookie name.
+func (e *EncryptedCodec) Decode(name, value string) (string, error) {
+	sealed, err := decode(value)
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return "", ErrInvalidCookie
+	}
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	plain, err := e.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(plain), nil
+}
+
+// deriveKey gives each codec its own 32 byte key, so signing and encryption never share one.
+func deriveKey(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,68 @@
+package cookies
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// ContextKey is where middlewares.CookieMiddleware stores the codec used by the helpers below.
+const ContextKey = "cookieCodec"
+
+// Set encodes value and stores it in the cookie described by policy.
+func Set(c *gin.Context, policy configs.CookiePolicy, value string, maxAge int) {
+	encoded, err := getCodec(c).Encode(policy.Name, value)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to encode cookie", "name", policy.Name, "error", err)
+		return
+	}
+	http.SetCookie(c.Writer, newCookie(policy, encoded, maxAge))
+}
+
+// Get returns the decoded value of the cookie described by policy.
+// A cookie that fails to decode is reported as missing.
+func Get(c *gin.Context, policy configs.CookiePolicy) (string, bool) {
+	cookie, err := c.Request.Cookie(policy.Name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	value, err := getCodec(c).Decode(policy.Name, cookie.Value)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// Has reports whether the request carries the cookie, without decoding it.
+func Has(c *gin.Context, policy configs.CookiePolicy) bool {
+	cookie, err := c.Request.Cookie(policy.Name)
+	return err == nil && cookie.Value != ""
+}
+
+// Clear expires the cookie described by policy.
+func Clear(c *gin.Context, policy configs.CookiePolicy) {
+	http.SetCookie(c.Writer, newCookie(policy, "", -1))
+}
+
+func newCookie(policy configs.CookiePolicy, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     policy.Name,
+		Value:    value,
+		Domain:   policy.Domain,
+		Path:     policy.Path,
+		MaxAge:   maxAge,
+		Secure:   policy.Secure,
+		HttpOnly: policy.HttpOnly,
+		SameSite: policy.SameSite,
+	}
+}
+
+// getCodec returns the codec set by middlewares.CookieMiddleware, falling back to plain values.
+func getCodec(c *gin.Context) Codec {
+	if codec, ok := c.Value(ContextKey).(Codec); ok {
+		return codec
+	}
+	return PlainCodec{}
+}
@@ -0,0 +1,63 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// MaildirMailer writes messages to a maildir on disk instead of sending them.
+// It is meant for development, the files can be opened with any mail client.
+type MaildirMailer struct {
+	path string
+	from string
+}
+
+// NewMaildirMailer creates the tmp, new and cur folders under path and returns a MaildirMailer.
+func NewMaildirMailer(path, from string) (Mailer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("maildir path must be set in the configuration")
+	}
+
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+
+	return &MaildirMailer{path: path, from: from}, nil
+}
+
+// Send writes the message to tmp and then moves it into new, so readers never see a partial file.
+func (m *MaildirMailer) Send(ctx context.Context, msg *Message) error {
+	body, err := msg.Build(m.from)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("failed to generate maildir file name: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%s.%s.eml", time.Now().UnixNano(), hex.EncodeToString(suffix), hostname)
+	tmpPath := filepath.Join(m.path, "tmp", name)
+	newPath := filepath.Join(m.path, "new", name)
+
+	if err := os.WriteFile(tmpPath, body, 0644); err != nil {
+		return fmt.Errorf("failed to write email to maildir: %w", err)
+	}
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		return fmt.Errorf("failed to deliver email to maildir: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("Email written to maildir", "subject", msg.Subject, "to", msg.To, "path", newPath)
+	return nil
+}
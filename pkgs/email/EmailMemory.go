@@ -0,0 +1,44 @@
+package email
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer captures messages in memory so tests can inspect what would have been sent.
+type MemoryMailer struct {
+	mu       sync.RWMutex
+	messages []Message
+}
+
+// NewMemoryMailer creates an empty MemoryMailer.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records a copy of the message.
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	captured := *msg
+	captured.To = append([]string(nil), msg.To...)
+	m.messages = append(m.messages, captured)
+	return nil
+}
+
+// Messages returns a copy of every captured message in the order they were sent.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset discards all captured messages.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
@@ -1,30 +1,156 @@
 package email
 
-// import (
-// 	"net/smtp"
-// )
-
-// type EmailService struct {
-// 	SMTPServer   string
-// 	SMTPPort     string
-// 	SMTPUsername string
-// 	SMTPPassword string
-// }
-
-// func NewEmailService() *EmailService {
-// 	return &EmailService{
-// 		SMTPServer:   "smtp.example.com",
-// 		SMTPPort:     "587",
-// 		SMTPUsername: "your-email@example.com",
-// 		SMTPPassword: "your-email-password",
-// 	}
-// }
-
-// func (e *EmailService) SendSMTPMail(from string, to []string, subject, body string) error {
-// 	auth := smtp.PlainAuth("", e.SMTPUsername, e.SMTPPassword, e.SMTPServer)
-// 	msg := []byte("To: " + to[0] + "\r\n" +
-// 		"Subject: " + subject + "\r\n" +
-// 		"\r\n" +
-// 		body + "\r\n")
-// 	return smtp.SendMail(e.SMTPServer+":"+e.SMTPPort, auth, from, to, msg)
-// }
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+)
+
+// Supported SMTP connection security modes
+const (
+	EncryptionNone     = "none"
+	EncryptionSTARTTLS = "starttls"
+	EncryptionTLS      = "tls"
+)
+
+// defaultSMTPTimeout bounds the whole SMTP exchange when no timeout is configured.
+const defaultSMTPTimeout = 10 * time.Second
+
+// SMTPMailer sends email through an SMTP relay.
+type SMTPMailer struct {
+	server     string
+	port       int
+	username   string
+	password   string
+	from       string
+	encryption string
+	timeout    time.Duration
+}
+
+// NewSMTPMailer creates a new SMTPMailer from the smtp configuration.
+func NewSMTPMailer() (Mailer, error) {
+	if configs.SMTPServer == "" || configs.SMTPPort == 0 {
+		return nil, errors.New("smtp server and port must be set in the configuration")
+	}
+	if configs.SMTPFrom == "" {
+		return nil, errors.New("smtp from address must be set in the configuration")
+	}
+
+	encryption := configs.SMTPEncryption
+	switch encryption {
+	case "":
+		encryption = EncryptionSTARTTLS
+	case EncryptionNone, EncryptionSTARTTLS, EncryptionTLS:
+	default:
+		return nil, fmt.Errorf("unknown smtp encryption %q", encryption)
+	}
+
+	timeout := configs.SMTPTimeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+
+	return &SMTPMailer{
+		server:     configs.SMTPServer,
+		port:       configs.SMTPPort,
+		username:   configs.SMTPUsername,
+		password:   configs.SMTPPassword,
+		from:       configs.SMTPFrom,
+		encryption: encryption,
+		timeout:    timeout,
+	}, nil
+}
+
+// Send delivers the message through the configured SMTP server.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	body, err := msg.Build(m.from)
+	if err != nil {
+		return err
+	}
+
+	// The exchange ends at the configured timeout or the context deadline, whichever is sooner
+	deadline := time.Now().Add(m.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	conn, err := m.dial(ctx, deadline)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set smtp deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, m.server)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if m.encryption == EncryptionSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(m.tlsConfig()); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.server)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, recipient := range msg.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s failed: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write email body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial opens the connection to the SMTP server, negotiating TLS immediately for implicit TLS.
+func (m *SMTPMailer) dial(ctx context.Context, deadline time.Time) (net.Conn, error) {
+	addr := net.JoinHostPort(m.server, fmt.Sprintf("%d", m.port))
+	dialer := &net.Dialer{Deadline: deadline}
+
+	if m.encryption == EncryptionTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: m.tlsConfig()}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// tlsConfig returns the TLS settings used for both STARTTLS and implicit TLS.
+func (m *SMTPMailer) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName: m.server,
+		MinVersion: tls.VersionTLS12,
+	}
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+// TemplateRenderer builds message bodies from paired templates, e.g. password_reset.txt and password_reset.html.
+type TemplateRenderer struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// NewTemplateRenderer parses every *.txt and *.html email template in dir.
+func NewTemplateRenderer(dir string) (*TemplateRenderer, error) {
+	renderer := &TemplateRenderer{
+		text: texttemplate.New("email"),
+		html: htmltemplate.New("email"),
+	}
+
+	textFiles, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list text email templates: %w", err)
+	}
+	if len(textFiles) > 0 {
+		if renderer.text, err = renderer.text.ParseFiles(textFiles...); err != nil {
+			return nil, fmt.Errorf("failed to parse text email templates: %w", err)
+		}
+	}
+
+	htmlFiles, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list html email templates: %w", err)
+	}
+	if len(htmlFiles) > 0 {
+		if renderer.html, err = renderer.html.ParseFiles(htmlFiles...); err != nil {
+			return nil, fmt.Errorf("failed to parse html email templates: %w", err)
+		}
+	}
+
+	if len(textFiles) == 0 && len(htmlFiles) == 0 {
+		return nil, fmt.Errorf("no email templates found in %s", dir)
+	}
+	return renderer, nil
+}
+
+// Render executes the text and html templates with the given base name and returns the message.
+// A template that only exists in one format produces a single part message.
+func (r *TemplateRenderer) Render(name string, to []string, subject string, data interface{}) (*Message, error) {
+	msg := &Message{To: to, Subject: subject}
+
+	if tmpl := r.text.Lookup(name + ".txt"); tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render email template %s.txt: %w", name, err)
+		}
+		msg.TextBody = buf.String()
+	}
+
+	if tmpl := r.html.Lookup(name + ".html"); tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render email template %s.html: %w", name, err)
+		}
+		msg.HTMLBody = buf.String()
+	}
+
+	if msg.TextBody == "" && msg.HTMLBody == "" {
+		return nil, fmt.Errorf("email template %s not found", name)
+	}
+	return msg, nil
+}
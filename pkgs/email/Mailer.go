@@ -0,0 +1,35 @@
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+)
+
+// Message is an outgoing email. When both bodies are set the message is sent as multipart/alternative.
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer creates the Mailer selected by the smtp.backend configuration.
+func NewMailer() (Mailer, error) {
+	switch configs.MailBackend {
+	case "", "smtp":
+		return NewSMTPMailer()
+	case "maildir":
+		return NewMaildirMailer(configs.MaildirPath, configs.SMTPFrom)
+	case "memory":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", configs.MailBackend)
+	}
+}
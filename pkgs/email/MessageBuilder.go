@@ -0,0 +1,114 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Build renders the message as an RFC 5322 email with the given sender.
+// Messages with both a text and an HTML body become multipart/alternative.
+func (m *Message) Build(from string) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, errors.New("email message has no recipients")
+	}
+	if m.TextBody == "" && m.HTMLBody == "" {
+		return nil, errors.New("email message has no body")
+	}
+
+	messageID, err := newMessageID(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from)
+	writeHeader(&buf, "To", strings.Join(m.To, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	// Single part messages carry the body directly
+	if m.TextBody == "" || m.HTMLBody == "" {
+		contentType, body := "text/plain; charset=UTF-8", m.TextBody
+		if m.HTMLBody != "" {
+			contentType, body = "text/html; charset=UTF-8", m.HTMLBody
+		}
+		writeHeader(&buf, "Content-Type", contentType)
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary()))
+	buf.WriteString("\r\n")
+
+	// Parts are ordered from least to most preferred as required by multipart/alternative
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", m.TextBody},
+		{"text/html; charset=UTF-8", m.HTMLBody},
+	}
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		if err := writeQuotedPrintable(partWriter, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeHeader writes a single header line, dropping any line breaks that could inject extra headers.
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	buf.WriteString(key + ": " + value + "\r\n")
+}
+
+// writeQuotedPrintable encodes the body as quoted-printable.
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("failed to encode email body: %w", err)
+	}
+	return qp.Close()
+}
+
+// newMessageID generates a unique Message-ID using the sender's domain.
+func newMessageID(from string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain), nil
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the value of every sensitive attribute.
+const Redacted = "[REDACTED]"
+
+// Attribute keys whose values are never written, whatever their case. Keys containing a sensitive word
+// are redacted, as are keys ending in a sensitive suffix, e.g. refresh_token but not token_duration.
+var (
+	sensitiveWords    = []string{"password", "passwd", "secret", "authorization", "cookie"}
+	sensitiveSuffixes = []string{"token", "tokens", "_key", "apikey", "recovery_code", "recovery_codes", "totp_code"}
+)
+
+// New returns a logger writing to w in the given format, text or json, that drops records below level
+// and redacts sensitive attributes.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       level,
+		ReplaceAttr: redact,
+	}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, must be json or text", format)
+}
+
+// redact hides the values of sensitive attributes and of anything that looks like a bearer credential.
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	if attr.Value.Kind() == slog.KindString {
+		scheme, _, found := strings.Cut(attr.Value.String(), " ")
+		if found && (strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "Basic")) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+	return attr
+}
+
+// IsSensitive reports whether values logged under key must be redacted.
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger when there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds the given attributes to every record.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// Fatal logs an error with the default logger and exits. The record points at the caller's source line.
+func Fatal(msg string, args ...any) {
+	logger := slog.Default()
+	if logger.Enabled(context.Background(), slog.LevelError) {
+		var pcs [1]uintptr
+		runtime.Callers(2, pcs[:])
+		record := slog.NewRecord(time.Now(), slog.LevelError, msg, pcs[0])
+		record.Add(args...)
+		logger.Handler().Handle(context.Background(), record)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,439 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeLayout is the timestamp rotated files get between the base name and the extension,
+// e.g. Server-20240131T235959.000.log. It sorts in time order.
+const backupTimeLayout = "20060102T150405.000"
+
+// legacyTimeLayout is the local time prefix of log files from before rotation, e.g. 20240131_235959_Server.log.
+// They are pruned like rotated files.
+const legacyTimeLayout = "20060102_150405"
+
+// rotateRetryDelay is how long writes go to the current file after a rotation failed before it is tried again.
+const rotateRetryDelay = time.Minute
+
+// RotateOptions configures when a RotatingFile is rotated and which rotated files are kept.
+// Zero values disable the corresponding rule.
+type RotateOptions struct {
+	MaxSize    int64         // rotate before a write would grow the file beyond this many bytes
+	Interval   time.Duration // rotate at every multiple of the interval, e.g. daily at midnight UTC for 24h
+	MaxBackups int           // number of rotated files kept
+	MaxAge     time.Duration // rotated files older than this are removed
+	Compress   bool          // gzip rotated files
+}
+
+// RotatingFile is a log file that rotates itself by size and time. Rotated files are renamed with a timestamp,
+// optionally compressed, and pruned by count and age in the background.
+// Reopen lets external tools such as logrotate move the file away and have writes continue in a new one.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	options  RotateOptions
+	file     *os.File // nil after a failed open, the next write tries again
+	size     int64
+	openedAt time.Time
+	retryAt  time.Time // no rotation is due before this time after a failed one
+	closed   bool      // set by Close
+	failing  bool      // an open failure was reported and none succeeded since
+
+	mill    chan struct{} // wakes the goroutine compressing and pruning rotated files
+	done    chan struct{}
+	signals chan os.Signal
+}
+
+// OpenRotatingFile opens or creates the log file at path, appending to what it already holds.
+func OpenRotatingFile(path string, options RotateOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &RotatingFile{
+		path:    path,
+		options: options,
+		mill:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	go f.runMill()
+	f.startMill() // prune what earlier runs left behind
+	return f, nil
+}
+
+// Write writes p to the file, rotating it first when it is due.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	if f.dueForRotation(int64(len(p)), time.Now()) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			// The old file is still open, the record goes there. Logging the failure would come back here.
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file, writing to the current one: %v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		return f.reopen()
+	}
+	return f.rotate()
+}
+
+// Reopen closes the file and opens the file at the same path again, creating it if it was moved away.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		f.file = nil
+	}
+	return f.reopen()
+}
+
+// ReopenOn reopens the file whenever the process receives one of the signals, usually SIGHUP.
+func (f *RotatingFile) ReopenOn(signals ...os.Signal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.signals != nil {
+		signal.Notify(f.signals, signals...)
+		return
+	}
+	f.signals = make(chan os.Signal, 1)
+	signal.Notify(f.signals, signals...)
+
+	go func(received <-chan os.Signal) {
+		for {
+			select {
+			case <-f.done:
+				return
+			case <-received:
+				// The file is reopened before logging, so the message lands in the new file
+				if err := f.Reopen(); err != nil {
+					slog.Error("Failed to reopen log file", "path", f.path, "error", err)
+				} else {
+					slog.Info("Log file reopened", "path", f.path)
+				}
+			}
+		}
+	}(f.signals)
+}
+
+// Close closes the file and stops the background work.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	if f.signals != nil {
+		signal.Stop(f.signals)
+	}
+	close(f.done)
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file for appending. An existing file counts as opened at its last write,
+// so a file left over from a previous interval is rotated on the first write.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = stat.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		f.openedAt = stat.ModTime()
+	}
+	return nil
+}
+
+// reopen opens the file again after it was closed for rotation or reopening. Until that succeeds every
+// write retries it. Only the first failure in a row is written to stderr, logging it would come back here.
+func (f *RotatingFile) reopen() error {
+	if err := f.open(); err != nil {
+		if !f.failing {
+			fmt.Fprintf(os.Stderr, "Failed to open log file, records are dropped until it opens: %v\n", err)
+			f.failing = true
+		}
+		return err
+	}
+	f.failing = false
+	return nil
+}
+
+// dueForRotation reports whether the file must be rotated before writing n more bytes.
+// A single write larger than MaxSize goes to an empty file rather than rotating forever.
+func (f *RotatingFile) dueForRotation(n int64, now time.Time) bool {
+	if f.size == 0 || now.Before(f.retryAt) {
+		return false
+	}
+	if f.options.MaxSize > 0 && f.size+n > f.options.MaxSize {
+		return true
+	}
+	if f.options.Interval > 0 {
+		return !now.Before(f.openedAt.Truncate(f.options.Interval).Add(f.options.Interval))
+	}
+	return false
+}
+
+// rotate renames the current file with a timestamp and opens a new one. Callers must hold the lock.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	if err := os.Rename(f.path, f.backupPath(time.Now())); err != nil && !os.IsNotExist(err) {
+		// Keep logging to the old file rather than losing records, and leave it be for a while
+		f.retryAt = time.Now().Add(rotateRetryDelay)
+		if reopenErr := f.reopen(); reopenErr != nil {
+			return reopenErr
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.reopen(); err != nil {
+		return err
+	}
+	f.startMill()
+	return nil
+}
+
+// backupPath names a rotated file after the time it was rotated. When a file of that name exists,
+// compressed or not, a counter is added, e.g. Server-20240131T235959.000-1.log.
+func (f *RotatingFile) backupPath(at time.Time) string {
+	dir, base, ext := f.nameParts()
+	stamp := at.UTC().Format(backupTimeLayout)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", base, stamp, ext))
+	for counter := 1; fileExists(path) || fileExists(path+".gz"); counter++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%s-%d%s", base, stamp, counter, ext))
+	}
+	return path
+}
+
+// fileExists reports whether anything exists at path.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// nameParts splits the path into its directory, base name and extension.
+func (f *RotatingFile) nameParts() (string, string, string) {
+	name := filepath.Base(f.path)
+	ext := filepath.Ext(name)
+	return filepath.Dir(f.path), strings.TrimSuffix(name, ext), ext
+}
+
+// startMill asks the background goroutine to compress and prune rotated files.
+func (f *RotatingFile) startMill() {
+	select {
+	case f.mill <- struct{}{}:
+	default: // already pending
+	}
+}
+
+// runMill compresses and prunes rotated files until the file is closed.
+// It writes its own failures to stderr, logging them could rotate the file again.
+func (f *RotatingFile) runMill() {
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-f.mill:
+			if err := f.millBackups(time.Now()); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to clean up rotated log files: %v\n", err)
+			}
+		}
+	}
+}
+
+// logBackup is a rotated file and the time it was rotated at.
+type logBackup struct {
+	path      string
+	rotatedAt time.Time
+	counter   int // orders files rotated within the same millisecond
+}
+
+// millBackups compresses rotated files and removes those beyond MaxBackups or older than MaxAge.
+func (f *RotatingFile) millBackups(now time.Time) error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for i, backup := range backups {
+		expired := f.options.MaxAge > 0 && now.Sub(backup.rotatedAt) > f.options.MaxAge
+		if (f.options.MaxBackups > 0 && i >= f.options.MaxBackups) || expired {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		if f.options.Compress && !strings.HasSuffix(backup.path, ".gz") {
+			if err := compressFile(backup.path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backups lists the rotated files and the legacy files from before rotation, newest first.
+func (f *RotatingFile) backups() ([]logBackup, error) {
+	dir, _, _ := f.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	backups := make([]logBackup, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if backup, ok := f.parseBackup(entry.Name()); ok {
+			backup.path = filepath.Join(dir, entry.Name())
+			backups = append(backups, backup)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].rotatedAt.Equal(backups[j].rotatedAt) {
+			return backups[i].counter > backups[j].counter
+		}
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// parseBackup reads the rotation time from the name of a rotated or legacy file, optionally gzipped.
+// It reports false for any other file.
+func (f *RotatingFile) parseBackup(name string) (logBackup, bool) {
+	_, base, ext := f.nameParts()
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasSuffix(name, ext) {
+		return logBackup{}, false
+	}
+	name = strings.TrimSuffix(name, ext)
+
+	// Legacy files: 20240131_235959_Server.log
+	if stamp, found := strings.CutSuffix(name, "_"+base); found {
+		rotatedAt, err := time.ParseInLocation(legacyTimeLayout, stamp, time.Local)
+		return logBackup{rotatedAt: rotatedAt}, err == nil
+	}
+
+	// Rotated files: Server-20240131T235959.000.log or Server-20240131T235959.000-1.log
+	stamp, found := strings.CutPrefix(name, base+"-")
+	if !found {
+		return logBackup{}, false
+	}
+	counter := 0
+	if before, suffix, hasCounter := strings.Cut(stamp, "-"); hasCounter {
+		n, err := strconv.Atoi(suffix)
+		if err != nil || n < 1 {
+			return logBackup{}, false
+		}
+		stamp, counter = before, n
+	}
+	rotatedAt, err := time.Parse(backupTimeLayout, stamp)
+	return logBackup{rotatedAt: rotatedAt, counter: counter}, err == nil
+}
+
+// compressFile replaces a file with a gzip compressed copy named path.gz.
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer source.Close()
+
+	// Write to a temporary name first so a crash never leaves a truncated .gz behind
+	target := path + ".gz"
+	tmp := target + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	writer := gzip.NewWriter(out)
+	_, err = io.Copy(writer, source)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename %s: %w", tmp, err)
+	}
+	source.Close()
+	return os.Remove(path)
+}
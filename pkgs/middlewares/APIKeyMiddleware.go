@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+)
+
+// APIKeyHeader carries a personal API key. Keys are also accepted as an Authorization Bearer value.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator checks a personal API key and returns its owner and the permissions the key may use.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key, ipAddress string) (*tokens.Payload, rbac.PermissionSet, error)
+}
+
+// APIKeyMiddleware authenticates requests that carry a personal API key and leaves every other
+// request to the next middleware, so it is meant to run in front of AuthTokenMiddleware.
+// It fills the same context values, and caches the key's scoped permissions for RequirePermission.
+func APIKeyMiddleware(authenticator APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := apiKeyFromRequest(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		payload, permissions, err := authenticator.AuthenticateAPIKey(c.Request.Context(), key, c.ClientIP())
+		if err != nil {
+			abortUnauthorized(c, "Invalid API key", "invalid_token", err.Error())
+			return
+		}
+
+		c.Set("userID", payload.UserID.String()) // Owner of the key
+		c.Set("username", payload.Username)      // Email of the owner
+		c.Set("role", payload.Role)              // Current role of the owner
+		c.Set("tokenPayload", payload)           // Unsigned payload describing the key
+		c.Set("permissions", permissions)        // Key scopes limited to the owner's permissions
+		c.Set("apiKeyID", payload.ID.String())   // Marks the request as authenticated by an API key
+		c.Set("tokenSource", "api_key")
+		setRequestUser(c, payload.UserID.String())
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated by an API key. It guards account security routes,
+// such as password changes and key management, that only a signed in superuser may use.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			strategy := responses.GetResponseStrategy(c)
+			strategy.Respond(c, map[string]interface{}{
+				"template": "forbidden.html",
+				"error":    "API keys cannot be used for this action, sign in instead",
+			}, http.StatusForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// apiKeyFromRequest returns the API key sent with the request, or an empty string.
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, credential, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if credential = strings.TrimSpace(credential); strings.EqualFold(scheme, "Bearer") && tokens.IsAPIKey(credential) {
+		return credential
+	}
+	return ""
+}
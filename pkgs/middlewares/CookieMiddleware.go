@@ -0,0 +1,14 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/pkgs/cookies"
+)
+
+// CookieMiddleware makes the cookie codec available to every handler that sets or reads cookies.
+func CookieMiddleware(codec cookies.Codec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(cookies.ContextKey, codec)
+		c.Next()
+	}
+}
@@ -1,45 +1,173 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
 	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/pkgs/cookies"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
 	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
 )
 
-func AuthTokenMiddleware(tokenManager tokens.TokenManager) gin.HandlerFunc {
+// Places an access token can be read from, listed in token.lookup in order of precedence
+const (
+	tokenSourceHeader = "header"
+	tokenSourceCookie = "cookie"
+)
+
+// authRealm is the realm announced in WWW-Authenticate challenges
+const authRealm = "htmx_GO"
+
+var errMalformedAuthorization = errors.New("Bearer authorization header carries no token")
+
+// SessionValidator checks server-side state that can invalidate a token before it expires.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, payload *tokens.Payload) error
+}
+
+// SessionRefresher exchanges a refresh token for a new token pair.
+type SessionRefresher interface {
+	RefreshSession(ctx context.Context, refreshToken string) (*tokens.TokenPair, error)
+}
+
+// SessionTracker records when and from where a session was last used.
+type SessionTracker interface {
+	RecordActivity(ctx context.Context, payload *tokens.Payload, userAgent, ipAddress string) error
+}
+
+func AuthTokenMiddleware(tokenManager tokens.TokenManager, sessionValidator SessionValidator, sessionRefresher SessionRefresher, sessionTracker SessionTracker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("SuperUserAuthorization")
+		// Already authenticated by APIKeyMiddleware
+		if _, ok := c.Get("apiKeyID"); ok {
+			c.Next()
+			return
+		}
+
+		token, source, err := extractToken(c)
+		if errors.Is(err, errMalformedAuthorization) {
+			abortUnauthorized(c, "Unauthorized", "invalid_request", err.Error())
+			return
+		}
+
+		// HTMX requests cannot follow a redirect to the login page, so an expired access token
+		// is replaced transparently while the refresh token is still valid
+		if c.GetHeader("HX-Request") == "true" && source != tokenSourceHeader {
+			expired := err != nil
+			if !expired {
+				_, validateErr := tokenManager.ValidateToken(token)
+				expired = errors.Is(validateErr, tokens.ErrExpiredToken)
+			}
+			if expired {
+				if pair, refreshed := refreshSession(c, sessionRefresher); refreshed {
+					token, err = pair.AccessToken, nil
+				}
+			}
+		}
+
 		if err != nil {
-			response := responses.NewResponse(
-				c,
-				http.StatusUnauthorized,
-				"Unauthorized",
-				nil,
-				"Failed to get token from cookie",
-			)
-			c.JSON(http.StatusUnauthorized, response)
-			c.Abort()
+			abortUnauthorized(c, "Unauthorized", "", "Failed to get token from the Authorization header or cookie")
 			return
 		}
 
 		payload, err := tokenManager.ValidateToken(token)
 		if err != nil {
-			response := responses.NewResponse(
-				c,
-				http.StatusUnauthorized,
-				"Invalid token",
-				nil,
-				err.Error(),
-			)
-			c.JSON(http.StatusUnauthorized, response)
-			c.Abort()
+			abortUnauthorized(c, "Invalid token", "invalid_token", err.Error())
+			return
+		}
+
+		// Only full access tokens may be used on protected routes
+		if payload.Purpose != tokens.PurposeAccess {
+			abortUnauthorized(c, "Invalid token", "invalid_token", "Token is not valid for this route")
 			return
 		}
 
-		c.Set("userID", payload.ID)         // Use payload ID or other necessary field
-		c.Set("username", payload.Username) // Optionally set username if needed
+		// Reject tokens whose session was revoked, e.g. after a password reset
+		if err := sessionValidator.ValidateSession(c.Request.Context(), payload); err != nil {
+			abortUnauthorized(c, "Invalid token", "invalid_token", err.Error())
+			return
+		}
+
+		// Failing to record activity should not fail the request
+		if err := sessionTracker.RecordActivity(c.Request.Context(), payload, c.Request.UserAgent(), c.ClientIP()); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Failed to record session activity", "error", err)
+		}
+
+		c.Set("userID", payload.UserID.String())       // Superuser ID from the token subject
+		c.Set("sessionID", payload.SessionID.String()) // Login session the token belongs to
+		c.Set("username", payload.Username)            // Email the superuser logged in with
+		c.Set("role", payload.Role)                    // Role at the time the token was issued
+		c.Set("tokenPayload", payload)                 // Full payload for middlewares that need more claims
+		c.Set("tokenSource", source)                   // Where the token came from, header or cookie
+		setRequestUser(c, payload.UserID.String())
 		c.Next()
 	}
 }
+
+// refreshSession rotates the refresh token cookie and stores the new tokens in the session cookies.
+func refreshSession(c *gin.Context, sessionRefresher SessionRefresher) (*tokens.TokenPair, bool) {
+	refreshToken, ok := cookies.Get(c, configs.RefreshCookie)
+	if !ok {
+		return nil, false
+	}
+
+	pair, err := sessionRefresher.RefreshSession(c.Request.Context(), refreshToken)
+	if err != nil {
+		return nil, false
+	}
+
+	SetSessionCookies(c, pair)
+	return pair, true
+}
+
+// extractToken returns the access token from the first configured source that carries one, and that source.
+func extractToken(c *gin.Context) (string, string, error) {
+	for _, source := range configs.TokenLookup {
+		switch source {
+		case tokenSourceHeader:
+			header := c.GetHeader("Authorization")
+			if header == "" {
+				continue
+			}
+			// Other schemes, e.g. Basic auth added by a proxy, are not ours to judge
+			scheme, token, _ := strings.Cut(header, " ")
+			if !strings.EqualFold(scheme, "Bearer") {
+				continue
+			}
+			if token = strings.TrimSpace(token); token == "" {
+				return "", source, errMalformedAuthorization
+			}
+			return token, source, nil
+		case tokenSourceCookie:
+			if token, ok := cookies.Get(c, configs.SessionCookie); ok {
+				return token, source, nil
+			}
+		}
+	}
+	return "", "", errors.New("no access token found")
+}
+
+// abortUnauthorized rejects the request with a 401 and a Bearer challenge (RFC 6750).
+// errorCode is left empty when no credentials were sent at all.
+func abortUnauthorized(c *gin.Context, message, errorCode, description string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", authRealm)
+	if errorCode != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", errorCode, description)
+	}
+	c.Header("WWW-Authenticate", challenge)
+
+	response := responses.NewResponse(
+		c,
+		http.StatusUnauthorized,
+		message,
+		nil,
+		description,
+	)
+	c.JSON(http.StatusUnauthorized, response)
+	c.Abort()
+}
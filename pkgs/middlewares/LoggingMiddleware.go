@@ -1,21 +1,54 @@
 package middlewares
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
 )
 
+// LoggingMiddleware gives every request a logger carrying its request ID, method and route, available to
+// handlers and services through logging.FromContext, and logs each request once it has been served.
+// Authentication adds the user ID. It must run after RequestIDMiddleware.
+//
+// Routes are logged as registered, e.g. /superuser/password-reset/:token, so tokens in the path
+// are never written. Only requests that match no route log the raw path.
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
 
+		route := c.FullPath()
+		attrs := []any{"request_id", c.GetString("RequestID"), "method", c.Request.Method}
+		if route != "" {
+			attrs = append(attrs, "route", route)
+		} else {
+			attrs = append(attrs, "path", c.Request.URL.Path)
+		}
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), attrs...))
+
 		// Process request
 		c.Next()
 
-		// Log request details
-		log.Printf("Request: %s %s | Status: %d | Duration: %v",
-			c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(startTime))
+		// Log request details at a level matching the outcome
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "Request served",
+			"status", status,
+			"duration", time.Since(startTime),
+			"client_ip", c.ClientIP(),
+		)
 	}
 }
+
+// setRequestUser adds the authenticated superuser's ID to the request logger.
+func setRequestUser(c *gin.Context, userID string) {
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", userID))
+}
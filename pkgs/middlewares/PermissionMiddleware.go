@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/pkgs/rbac"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+)
+
+// PermissionResolver loads the permissions granted to the superuser a token was issued to.
+type PermissionResolver interface {
+	ResolvePermissions(ctx context.Context, payload *tokens.Payload) (rbac.PermissionSet, error)
+}
+
+// RequirePermission only lets the request through when the caller holds every given permission.
+// It must run after AuthTokenMiddleware, which stores the token payload in the context.
+func RequirePermission(resolver PermissionResolver, permissions ...rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissionSet, err := callerPermissions(c, resolver)
+		if err != nil || !permissionSet.Has(permissions...) {
+			strategy := responses.GetResponseStrategy(c)
+			strategy.Respond(c, map[string]interface{}{
+				"template": "forbidden.html",
+				"error":    "You do not have permission to perform this action",
+			}, http.StatusForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// callerPermissions resolves the caller's permissions once per request and caches them in the context.
+func callerPermissions(c *gin.Context, resolver PermissionResolver) (rbac.PermissionSet, error) {
+	if cached, exists := c.Get("permissions"); exists {
+		if permissionSet, ok := cached.(rbac.PermissionSet); ok {
+			return permissionSet, nil
+		}
+	}
+
+	value, exists := c.Get("tokenPayload")
+	payload, ok := value.(*tokens.Payload)
+	if !exists || !ok {
+		return nil, tokens.ErrInvalidToken
+	}
+
+	permissionSet, err := resolver.ResolvePermissions(c.Request.Context(), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set("permissions", permissionSet)
+	return permissionSet, nil
+}
@@ -0,0 +1,140 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// maxRateLimitBody caps how much of a JSON body is read to find the account of a request.
+const maxRateLimitBody = 1 << 20
+
+// RateLimitMiddleware applies the named policy from the rate_limit configuration. Every rule of the
+// policy has its own token bucket per key, and the request is rejected with a 429 once any of them is empty.
+// The RateLimit-* headers describe the rule closest to its limit.
+func RateLimitMiddleware(store RateLimitStore, policy string) gin.HandlerFunc {
+	rules := configs.RateLimitPolicies[policy]
+	if !configs.RateLimitEnabled || len(rules) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	descriptions := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		descriptions = append(descriptions, fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Window.Seconds())))
+	}
+	policyHeader := strings.Join(descriptions, ", ")
+
+	return func(c *gin.Context) {
+		var tightest *RateLimitResult
+		for i, rule := range rules {
+			value := rateLimitKey(c, rule.By)
+			if value == "" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%d:%s:%s", policy, i, rule.By, value)
+			result, err := store.Take(c.Request.Context(), key, rule.Limit, rule.Window)
+			if err != nil {
+				// A broken store should not take the whole site down
+				logging.FromContext(c.Request.Context()).Error("Rate limit store failed", "policy", policy, "error", err)
+				continue
+			}
+
+			if tightest == nil || tighter(result, *tightest) {
+				tightest = &result
+			}
+		}
+
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset)))
+
+		if !tightest.Allowed {
+			retryAfter := ceilSeconds(tightest.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+			strategy := responses.GetResponseStrategy(c)
+			strategy.Respond(c, map[string]interface{}{
+				"template":    "rate_limited.html",
+				"error":       fmt.Sprintf("Too many requests, please try again in %s", time.Duration(retryAfter)*time.Second),
+				"retry_after": retryAfter,
+			}, http.StatusTooManyRequests)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tighter reports whether a is closer to its limit than b. Rejections always win.
+func tighter(a, b RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// rateLimitKey returns the value requests are grouped by, or an empty string if the request has none.
+func rateLimitKey(c *gin.Context, by string) string {
+	switch by {
+	case "ip":
+		return c.ClientIP()
+	case "account":
+		return accountFromRequest(c)
+	case "user":
+		return c.GetString("userID")
+	}
+	return ""
+}
+
+// accountFromRequest returns the email a login or recovery request is about, without consuming the body.
+func accountFromRequest(c *gin.Context) string {
+	var email string
+	if c.ContentType() == gin.MIMEJSON {
+		original := c.Request.Body
+		body, err := io.ReadAll(io.LimitReader(original, maxRateLimitBody))
+		// Whatever was read is put back in front of the rest of the body for the handler
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		if err != nil {
+			return ""
+		}
+
+		var fields struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(body, &fields) == nil {
+			email = fields.Email
+		}
+	} else {
+		email = c.PostForm("email")
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
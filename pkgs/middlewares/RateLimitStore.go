@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitStore keeps the token buckets of the rate limiter. The in-memory store only sees the requests
+// of one server, a shared store (e.g. Redis) can implement the same interface for several instances.
+type RateLimitStore interface {
+	// Take removes a token from the bucket of key, which holds up to limit tokens and refills completely every window.
+	Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitResult describes a bucket after a request tried to take a token from it.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token is available, zero when the request was allowed
+}
+
+type rateLimitBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	window    time.Duration
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+}
+
+type inMemoryRateLimitStore struct {
+	shards []*rateLimitShard
+}
+
+// NewInMemoryRateLimitStore creates a rate limit store that spreads its buckets over shards,
+// each with its own lock. Idle buckets are swept every sweepInterval until ctx is done.
+func NewInMemoryRateLimitStore(ctx context.Context, shards int, sweepInterval time.Duration) RateLimitStore {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &inMemoryRateLimitStore{shards: make([]*rateLimitShard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &rateLimitShard{buckets: make(map[string]*rateLimitBucket)}
+	}
+
+	if sweepInterval > 0 {
+		go s.sweep(ctx, sweepInterval)
+	}
+	return s
+}
+
+// Take removes a token from the bucket of key in memory.
+func (s *inMemoryRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(limit)
+	refillRate := capacity / window.Seconds() // tokens per second
+
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: capacity, updatedAt: now, window: window}
+		shard.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*refillRate)
+	bucket.updatedAt = now
+
+	result := RateLimitResult{Limit: limit}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / refillRate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsToDuration((capacity - bucket.tokens) / refillRate)
+	return result, nil
+}
+
+// shard picks the shard that holds the bucket of key.
+func (s *inMemoryRateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// sweep periodically removes buckets that have refilled completely, they are the same as a new bucket.
+func (s *inMemoryRateLimitStore) sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, shard := range s.shards {
+				shard.mu.Lock()
+				for key, bucket := range shard.buckets {
+					if now.Sub(bucket.updatedAt) >= bucket.window {
+						delete(shard.buckets, key)
+					}
+				}
+				shard.mu.Unlock()
+			}
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// RecoveryMiddleware turns a panic in a handler into a 500 response and logs it at error level with the
+// request logger, so the record carries the request ID and user. LoggingMiddleware never sees these requests,
+// the panic unwinds past it. Broken client connections are handled by gin and not logged as panics.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("Request panicked",
+			"panic", recovered,
+			"status", http.StatusInternalServerError,
+			"client_ip", c.ClientIP(),
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -1,10 +1,9 @@
 package middlewares
 
 import (
-	"log"
-
 	"github.com/gin-gonic/gin"
 	"github.com/lordofthemind/htmx_GO/internals/responses"
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
 )
 
 func ResponseStrategyMiddleware() gin.HandlerFunc {
@@ -15,15 +14,15 @@ func ResponseStrategyMiddleware() gin.HandlerFunc {
 		case acceptHeader == "text/html" || c.GetHeader("HX-Request") == "true":
 			// For HTML or HTMX requests, no template specified here
 			c.Set("responseStrategy", &responses.HTMLResponseStrategy{})
-			log.Println("Response Strategy set to HTML Response")
+			logging.FromContext(c.Request.Context()).Debug("Response strategy set", "strategy", "html")
 		case acceptHeader == "application/json":
 			// For JSON requests
 			c.Set("responseStrategy", &responses.JSONResponseStrategy{})
-			log.Println("Response Strategy set to JSON Response")
+			logging.FromContext(c.Request.Context()).Debug("Response strategy set", "strategy", "json")
 		default:
 			// Fallback to HTMLResponseStrategy for any other cases
 			c.Set("responseStrategy", &responses.HTMLResponseStrategy{Template: "default.html"})
-			log.Println("Response Strategy set to HTML Response in default")
+			logging.FromContext(c.Request.Context()).Debug("Response strategy set", "strategy", "html", "default", true)
 		}
 
 		c.Next()
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/lordofthemind/htmx_GO/pkgs/cookies"
+	"github.com/lordofthemind/htmx_GO/pkgs/tokens"
+)
+
+// SetSessionCookies stores the tokens of a started or refreshed session in HttpOnly cookies.
+// The refresh cookie is left untouched when the pair does not carry a rotated refresh token.
+func SetSessionCookies(c *gin.Context, pair *tokens.TokenPair) {
+	cookies.Set(c, configs.SessionCookie, pair.AccessToken, int(configs.TokenAccessDuration.Seconds()))
+
+	if pair.RefreshToken != "" {
+		cookies.Set(c, configs.RefreshCookie, pair.RefreshToken, int(time.Until(pair.RefreshExpiresAt).Seconds()))
+	}
+}
+
+// ClearSessionCookies removes both session cookies.
+func ClearSessionCookies(c *gin.Context) {
+	cookies.Clear(c, configs.SessionCookie)
+	cookies.Clear(c, configs.RefreshCookie)
+}
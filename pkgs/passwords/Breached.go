@@ -0,0 +1,79 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rangePrefixLength is the number of hex characters of the SHA-1 hash used to pick a range,
+// the same split as the Have I Been Pwned range API.
+const rangePrefixLength = 5
+
+// BreachedChecker reports whether a password is known from a data breach.
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// HashRangeList is a local breached password list in the Have I Been Pwned format: one upper case
+// SHA-1 hash per line, optionally followed by ":<count>". Hashes are grouped into ranges by their
+// first five characters, so a lookup only ever compares suffixes within one range, as the range API does.
+type HashRangeList struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadHashRangeList reads a breached password hash file. Empty lines and lines starting with # are ignored.
+func LoadHashRangeList(path string) (*HashRangeList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &HashRangeList{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(entry, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return list, nil
+}
+
+func (l *HashRangeList) add(hash string) {
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+	if l.ranges[prefix] == nil {
+		l.ranges[prefix] = make(map[string]struct{})
+	}
+	l.ranges[prefix][suffix] = struct{}{}
+}
+
+// Len returns the number of hashes in the list.
+func (l *HashRangeList) Len() int {
+	count := 0
+	for _, suffixes := range l.ranges {
+		count += len(suffixes)
+	}
+	return count
+}
+
+// IsBreached reports whether the SHA-1 hash of password is in the list.
+func (l *HashRangeList) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := l.ranges[hash[:rangePrefixLength]][hash[rangePrefixLength:]]
+	return found, nil
+}
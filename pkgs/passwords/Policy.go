@@ -0,0 +1,171 @@
+package passwords
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+)
+
+// Rules reported in a Violation
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUpper        = "upper"
+	RuleLower        = "lower"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+	RuleHistory      = "history"
+)
+
+// personalInfoMinLength ignores usernames and email names too short to matter, e.g. "al".
+const personalInfoMinLength = 3
+
+// Violation is a single password rule that was not met.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password broke.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Policy checks new passwords against the rules in the password_policy configuration.
+type Policy struct {
+	minLength          int
+	maxLength          int
+	requireUpper       bool
+	requireLower       bool
+	requireDigit       bool
+	requireSymbol      bool
+	rejectPersonalInfo bool
+	history            int
+	breached           BreachedChecker
+}
+
+// NewPolicy creates the password policy and loads the breached password list if it is enabled.
+func NewPolicy() (*Policy, error) {
+	policy := &Policy{
+		minLength:          configs.PasswordMinLength,
+		maxLength:          configs.PasswordMaxLength,
+		requireUpper:       configs.PasswordRequireUpper,
+		requireLower:       configs.PasswordRequireLower,
+		requireDigit:       configs.PasswordRequireDigit,
+		requireSymbol:      configs.PasswordRequireSymbol,
+		rejectPersonalInfo: configs.PasswordRejectPersonal,
+		history:            configs.PasswordHistory,
+	}
+
+	if configs.PasswordBreachedList != "" {
+		list, err := LoadHashRangeList(configs.PasswordBreachedList)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Loaded breached password list", "hashes", list.Len())
+		policy.breached = list
+	}
+	return policy, nil
+}
+
+// History returns how many previous passwords may not be reused.
+func (p *Policy) History() int {
+	return p.history
+}
+
+// Validate checks a new password for the account with the given username and email.
+// It returns a *PolicyError listing every broken rule.
+func (p *Policy) Validate(password, username, email string) error {
+	var violations []Violation
+	add := func(rule, message string) {
+		violations = append(violations, Violation{Rule: rule, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength {
+		add(RuleMinLength, fmt.Sprintf("Password must be at least %d characters long", p.minLength))
+	}
+	if p.maxLength > 0 && len(password) > p.maxLength {
+		add(RuleMaxLength, fmt.Sprintf("Password must be at most %d characters long", p.maxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.requireUpper && !hasUpper {
+		add(RuleUpper, "Password must contain an uppercase letter")
+	}
+	if p.requireLower && !hasLower {
+		add(RuleLower, "Password must contain a lowercase letter")
+	}
+	if p.requireDigit && !hasDigit {
+		add(RuleDigit, "Password must contain a digit")
+	}
+	if p.requireSymbol && !hasSymbol {
+		add(RuleSymbol, "Password must contain a symbol")
+	}
+
+	if p.rejectPersonalInfo && containsPersonalInfo(password, username, email) {
+		add(RulePersonalInfo, "Password must not contain your username or email address")
+	}
+
+	// A broken list must not stop people from changing their password
+	if p.breached != nil {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			slog.Error("Failed to check breached password list", "error", err)
+		} else if breached {
+			add(RuleBreached, "Password has appeared in a data breach, choose a different one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// HistoryViolation is the error returned when a password matches one of the previous passwords.
+func (p *Policy) HistoryViolation() error {
+	return &PolicyError{Violations: []Violation{{
+		Rule:    RuleHistory,
+		Message: fmt.Sprintf("Password must not match any of your last %d passwords", p.history),
+	}}}
+}
+
+// containsPersonalInfo reports whether password contains the username, the email address or its local part.
+func containsPersonalInfo(password, username, email string) bool {
+	password = strings.ToLower(password)
+	localPart, _, _ := strings.Cut(email, "@")
+	for _, value := range []string{username, email, localPart} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if utf8.RuneCountInString(value) >= personalInfoMinLength && strings.Contains(password, value) {
+			return true
+		}
+	}
+	return false
+}
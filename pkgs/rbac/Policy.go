@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+)
+
+// Permission is a named action a superuser may perform, e.g. "files:upload".
+type Permission string
+
+// Permissions checked by the superuser routes
+const (
+	PermDashboardView   Permission = "dashboard:view"
+	PermProfileRead     Permission = "profile:read"
+	PermProfileWrite    Permission = "profile:write"
+	PermFilesUpload     Permission = "files:upload"
+	PermFilesDownload   Permission = "files:download"
+	PermSuperusersRead  Permission = "superusers:read"
+	PermSuperusersWrite Permission = "superusers:write"
+	PermRolesManage     Permission = "roles:manage"
+	PermAuditRead       Permission = "audit:read"
+)
+
+// AllPermissions lists every permission checked by the routes, in the order they are declared.
+func AllPermissions() []Permission {
+	return []Permission{
+		PermDashboardView,
+		PermProfileRead,
+		PermProfileWrite,
+		PermFilesUpload,
+		PermFilesDownload,
+		PermSuperusersRead,
+		PermSuperusersWrite,
+		PermRolesManage,
+		PermAuditRead,
+	}
+}
+
+// PermAll grants every permission when listed for a role or group.
+const PermAll Permission = "*"
+
+// PermissionSet is the set of permissions granted to a superuser.
+type PermissionSet map[Permission]struct{}
+
+// Has reports whether every given permission is granted.
+func (s PermissionSet) Has(permissions ...Permission) bool {
+	if _, ok := s[PermAll]; ok {
+		return true
+	}
+	for _, permission := range permissions {
+		if _, ok := s[permission]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Covers reports whether s grants every permission of other. Only a wildcard covers a wildcard.
+func (s PermissionSet) Covers(other PermissionSet) bool {
+	for permission := range other {
+		if !s.Has(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// Policy maps roles and permission groups to the permissions they grant.
+type Policy struct {
+	roles       map[string][]Permission
+	groups      map[string][]Permission
+	defaultRole string
+}
+
+// NewPolicy creates a Policy from the rbac configuration.
+func NewPolicy() (*Policy, error) {
+	if len(configs.RBACRoles) == 0 {
+		return nil, errors.New("at least one role must be set in the rbac configuration")
+	}
+
+	policy := &Policy{
+		roles:       toPermissions(configs.RBACRoles),
+		groups:      toPermissions(configs.RBACGroups),
+		defaultRole: configs.RBACDefaultRole,
+	}
+
+	if !policy.HasRole(policy.defaultRole) {
+		return nil, fmt.Errorf("default role %q is not defined in the rbac configuration", policy.defaultRole)
+	}
+	return policy, nil
+}
+
+// DefaultRole returns the role assigned to newly registered superusers.
+func (p *Policy) DefaultRole() string {
+	return p.defaultRole
+}
+
+// HasRole reports whether the role is defined.
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+// Roles returns the names of all defined roles in alphabetical order.
+func (p *Policy) Roles() []string {
+	roles := make([]string, 0, len(p.roles))
+	for role := range p.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// RolePermissions returns the permissions granted directly by a role.
+func (p *Policy) RolePermissions(role string) []Permission {
+	return p.roles[role]
+}
+
+// PermissionsFor returns the permissions of a role combined with those of its groups.
+// Superusers without a role, such as accounts created before roles were assigned, get the default role.
+func (p *Policy) PermissionsFor(role string, groups []string) PermissionSet {
+	if role == "" {
+		role = p.defaultRole
+	}
+
+	set := PermissionSet{}
+	for _, permission := range p.roles[role] {
+		set[permission] = struct{}{}
+	}
+	for _, group := range groups {
+		for _, permission := range p.groups[group] {
+			set[permission] = struct{}{}
+		}
+	}
+	return set
+}
+
+// toPermissions converts the configured string lists into permissions.
+func toPermissions(config map[string][]string) map[string][]Permission {
+	result := make(map[string][]Permission, len(config))
+	for name, permissions := range config {
+		for _, permission := range permissions {
+			result[name] = append(result[name], Permission(permission))
+		}
+	}
+	return result
+}
@@ -0,0 +1,12 @@
+package tokens
+
+import "strings"
+
+// APIKeyTag starts every personal API key, so keys are recognisable in logs and secret scanners
+// and can be told apart from signed tokens. Keys look like hgo_<prefix>_<secret>.
+const APIKeyTag = "hgo"
+
+// IsAPIKey reports whether a credential has the shape of a personal API key.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyTag+"_")
+}
@@ -0,0 +1,42 @@
+package tokens
+
+import (
+	"encoding/base64"
+)
+
+// JWK is the public part of an Ed25519 signing key in JSON Web Key format (RFC 8037).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySetProvider is implemented by token managers whose tokens can be verified with published public keys.
+type KeySetProvider interface {
+	JWKS() JWKS
+}
+
+// JWKS returns the public keys that still verify tokens, newest first.
+// alg is left empty for keys that are not used with JOSE, e.g. PASETO v2.public.
+func (k *Keyring) JWKS(alg string) JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.ActiveKeys() {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.PublicKey),
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: alg,
+		})
+	}
+	return set
+}
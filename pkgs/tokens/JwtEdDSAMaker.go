@@ -0,0 +1,81 @@
+package tokens
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTEdDSAMaker signs JWTs with the Ed25519 keys of a keyring.
+// The kid header names the key, so tokens stay valid across rotations until their key is retired.
+type JWTEdDSAMaker struct {
+	keyring *Keyring
+}
+
+// NewJWTEdDSAMaker creates a new JWTEdDSAMaker
+func NewJWTEdDSAMaker(keyring *Keyring) (TokenManager, error) {
+	if keyring == nil {
+		return nil, errors.New("a keyring is required for asymmetric tokens")
+	}
+	return &JWTEdDSAMaker{keyring: keyring}, nil
+}
+
+// GenerateToken creates a new token for the given claims, signed with the current key
+func (j *JWTEdDSAMaker) GenerateToken(claims Claims) (string, *Payload, error) {
+	payload, err := NewPayload(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := j.keyring.Current()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claimsFromPayload(payload))
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, payload, nil
+}
+
+// ValidateToken checks the signature against the key named by the kid header
+func (j *JWTEdDSAMaker) ValidateToken(tokenString string) (*Payload, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		publicKey, ok := j.keyring.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown or retired signing key")
+		}
+		return publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := payloadFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// JWKS returns the public keys other services can verify our tokens with
+func (j *JWTEdDSAMaker) JWKS() JWKS {
+	return j.keyring.JWKS(jwt.SigningMethodEdDSA.Alg())
+}
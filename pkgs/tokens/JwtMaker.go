@@ -2,45 +2,41 @@ package tokens
 
 import (
 	"errors"
-	"time"
+	"fmt"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/lordofthemind/htmx_GO/internals/configs"
 )
 
+// jwtKeyMinLength is the shortest HS256 key accepted, as long as the hash output.
+const jwtKeyMinLength = 32
+
 type JWTMaker struct{}
 
 // NewJWTMaker creates a new JWTMaker
 func NewJWTMaker() (TokenManager, error) {
-	if len(configs.TokenSymmetricKey) == 0 {
-		return nil, errors.New("symmetric key must be set in the configuration")
+	if len(configs.TokenSymmetricKey) < jwtKeyMinLength {
+		return nil, fmt.Errorf("symmetric key must be at least %d bytes", jwtKeyMinLength)
 	}
 	return &JWTMaker{}, nil
 }
 
-// GenerateToken creates a new token for a specific user
-func (j *JWTMaker) GenerateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// GenerateToken creates a new token for the given claims
+func (j *JWTMaker) GenerateToken(claims Claims) (string, *Payload, error) {
+	payload, err := NewPayload(claims)
 	if err != nil {
-		return "", err
-	}
-
-	claims := jwt.MapClaims{
-		"id":         payload.ID.String(),
-		"username":   payload.Username,
-		"issued_at":  payload.IssuedAt.Unix(),
-		"expired_at": payload.ExpiredAt.Unix(),
+		return "", nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claimsFromPayload(payload))
 
 	tokenString, err := token.SignedString([]byte(configs.TokenSymmetricKey))
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return tokenString, nil
+	return tokenString, payload, nil
 }
 
 // ValidateToken checks if the token is valid or not
@@ -52,6 +48,9 @@ func (j *JWTMaker) ValidateToken(tokenString string) (*Payload, error) {
 		return []byte(configs.TokenSymmetricKey), nil
 	})
 
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	}
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
@@ -61,11 +60,9 @@ func (j *JWTMaker) ValidateToken(tokenString string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	payload := &Payload{
-		ID:        uuid.MustParse(claims["id"].(string)),
-		Username:  claims["username"].(string),
-		IssuedAt:  time.Unix(int64(claims["issued_at"].(float64)), 0),
-		ExpiredAt: time.Unix(int64(claims["expired_at"].(float64)), 0),
+	payload, err := payloadFromClaims(claims)
+	if err != nil {
+		return nil, err
 	}
 
 	err = payload.Valid()
@@ -75,3 +72,63 @@ func (j *JWTMaker) ValidateToken(tokenString string) (*Payload, error) {
 
 	return payload, nil
 }
+
+// claimsFromPayload converts a payload into JWT claims.
+// Registered claim names are used where one exists so other JWT libraries can read the token.
+func claimsFromPayload(payload *Payload) jwt.MapClaims {
+	return jwt.MapClaims{
+		"jti":      payload.ID.String(),
+		"sub":      payload.UserID.String(),
+		"sid":      payload.SessionID.String(),
+		"username": payload.Username,
+		"role":     payload.Role,
+		"purpose":  payload.Purpose,
+		"iat":      payload.IssuedAt.Unix(),
+		"exp":      payload.ExpiredAt.Unix(),
+	}
+}
+
+// payloadFromClaims reads the payload fields from validated JWT claims
+func payloadFromClaims(claims jwt.MapClaims) (*Payload, error) {
+	tokenID, _ := claims["jti"].(string)
+	subject, _ := claims["sub"].(string)
+	session, _ := claims["sid"].(string)
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	purpose, _ := claims["purpose"].(string)
+
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens that do not belong to a login session, e.g. email verification links, carry a nil session ID
+	sessionID, err := uuid.Parse(session)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+	expiredAt, err := claims.GetExpirationTime()
+	if err != nil || expiredAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Payload{
+		ID:        id,
+		UserID:    userID,
+		SessionID: sessionID,
+		Username:  username,
+		Role:      role,
+		Purpose:   purpose,
+		IssuedAt:  issuedAt.Time,
+		ExpiredAt: expiredAt.Time,
+	}, nil
+}
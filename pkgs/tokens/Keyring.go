@@ -0,0 +1,238 @@
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lordofthemind/htmx_GO/pkgs/logging"
+)
+
+// SigningKey is an Ed25519 key pair identified by its key ID (kid).
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	CreatedAt  time.Time
+}
+
+// storedKey is the on-disk form of a SigningKey, the private key is kept as its seed.
+type storedKey struct {
+	ID        string    `json:"kid"`
+	Seed      string    `json:"seed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Keyring holds the signing keys used by the asymmetric token makers.
+// New tokens are always signed with the newest key. Older keys keep verifying tokens until they are retired,
+// which happens once a newer key has been in use for longer than the retirement period.
+type Keyring struct {
+	mu               sync.RWMutex
+	path             string
+	keys             []*SigningKey // oldest first
+	rotationInterval time.Duration
+	retireAfter      time.Duration
+}
+
+// LoadKeyring reads the keyring stored at path, creating it with a fresh key if it does not exist yet.
+// retireAfter must be longer than the lifetime of any token signed with the keyring.
+func LoadKeyring(path string, rotationInterval, retireAfter time.Duration) (*Keyring, error) {
+	if path == "" {
+		return nil, errors.New("keyring path must be set in the configuration")
+	}
+	if rotationInterval <= 0 || retireAfter <= 0 {
+		return nil, errors.New("keyring rotation interval and retirement period must be positive")
+	}
+
+	keyring := &Keyring{
+		path:             path,
+		rotationInterval: rotationInterval,
+		retireAfter:      retireAfter,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := keyring.Rotate(); err != nil {
+			return nil, err
+		}
+		return keyring, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var stored []storedKey
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+	for _, sk := range stored {
+		seed, err := base64.RawURLEncoding.DecodeString(sk.Seed)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid seed for key %s in keyring", sk.ID)
+		}
+		privateKey := ed25519.NewKeyFromSeed(seed)
+		keyring.keys = append(keyring.keys, &SigningKey{
+			ID:         sk.ID,
+			PrivateKey: privateKey,
+			PublicKey:  privateKey.Public().(ed25519.PublicKey),
+			CreatedAt:  sk.CreatedAt,
+		})
+	}
+	sort.Slice(keyring.keys, func(i, j int) bool {
+		return keyring.keys[i].CreatedAt.Before(keyring.keys[j].CreatedAt)
+	})
+
+	if len(keyring.keys) == 0 {
+		if err := keyring.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return keyring, nil
+}
+
+// Current returns the key new tokens are signed with.
+func (k *Keyring) Current() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.keys[len(k.keys)-1]
+}
+
+// Lookup returns the public key for a key ID, as long as the key has not been retired.
+func (k *Keyring) Lookup(kid string) (ed25519.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	for i, key := range k.keys {
+		if key.ID == kid && !k.retired(i, now) {
+			return key.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// ActiveKeys returns every key that still verifies tokens, newest first.
+func (k *Keyring) ActiveKeys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	var active []*SigningKey
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.retired(i, now) {
+			active = append(active, k.keys[i])
+		}
+	}
+	return active
+}
+
+// retired reports whether the key at index i has been superseded for longer than the retirement period.
+// Callers must hold the lock.
+func (k *Keyring) retired(i int, now time.Time) bool {
+	if i == len(k.keys)-1 {
+		return false
+	}
+	return now.Sub(k.keys[i+1].CreatedAt) > k.retireAfter
+}
+
+// Rotate adds a new signing key, drops retired keys and saves the keyring.
+func (k *Keyring) Rotate() error {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	// The key ID is derived from the public key so it is stable across restarts
+	sum := sha256.Sum256(publicKey)
+	key := &SigningKey{
+		ID:         base64.RawURLEncoding.EncodeToString(sum[:12]),
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		CreatedAt:  time.Now(),
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = append(k.keys, key)
+	now := time.Now()
+	kept := make([]*SigningKey, 0, len(k.keys))
+	for i, existing := range k.keys {
+		if !k.retired(i, now) {
+			kept = append(kept, existing)
+		}
+	}
+	k.keys = kept
+
+	slog.Info("Token signing key rotated", "kid", key.ID)
+	return k.save()
+}
+
+// RotateIfDue rotates the keyring when the current key is older than the rotation interval.
+func (k *Keyring) RotateIfDue() error {
+	if time.Since(k.Current().CreatedAt) < k.rotationInterval {
+		return nil
+	}
+	return k.Rotate()
+}
+
+// StartRotation checks every checkInterval whether the signing key is due for rotation until ctx is done.
+func (k *Keyring) StartRotation(ctx context.Context, checkInterval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.RotateIfDue(); err != nil {
+					logging.FromContext(ctx).Error("Failed to rotate token signing key", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// save writes the keyring to a temporary file and renames it into place. Callers must hold the lock.
+func (k *Keyring) save() error {
+	stored := make([]storedKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		stored = append(stored, storedKey{
+			ID:        key.ID,
+			Seed:      base64.RawURLEncoding.EncodeToString(key.PrivateKey.Seed()),
+			CreatedAt: key.CreatedAt,
+		})
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keyring: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("failed to create keyring directory: %w", err)
+	}
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		return fmt.Errorf("failed to save keyring: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package tokens
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/lordofthemind/htmx_GO/internals/configs"
 	"github.com/o1egl/paseto"
@@ -28,12 +27,17 @@ func NewPasetoMaker() (TokenManager, error) {
 }
 
 // GenerateToken creates a new token for a specific user
-func (maker *PasetoMaker) GenerateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) GenerateToken(claims Claims) (string, *Payload, error) {
+	payload, err := NewPayload(claims)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
 }
 
 // ValidateToken checks if the token is valid or not
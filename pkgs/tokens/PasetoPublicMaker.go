@@ -0,0 +1,71 @@
+package tokens
+
+import (
+	"errors"
+
+	"github.com/o1egl/paseto"
+)
+
+// pasetoFooter is the unencrypted but signed footer of a v2.public token, naming the signing key
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoPublicMaker signs PASETO v2.public tokens with the Ed25519 keys of a keyring
+type PasetoPublicMaker struct {
+	paseto  *paseto.V2
+	keyring *Keyring
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker
+func NewPasetoPublicMaker(keyring *Keyring) (TokenManager, error) {
+	if keyring == nil {
+		return nil, errors.New("a keyring is required for asymmetric tokens")
+	}
+	return &PasetoPublicMaker{paseto: paseto.NewV2(), keyring: keyring}, nil
+}
+
+// GenerateToken creates a new token for the given claims, signed with the current key
+func (maker *PasetoPublicMaker) GenerateToken(claims Claims) (string, *Payload, error) {
+	payload, err := NewPayload(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := maker.keyring.Current()
+	token, err := maker.paseto.Sign(key.PrivateKey, payload, pasetoFooter{KeyID: key.ID})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+// ValidateToken checks the signature against the key named in the footer
+func (maker *PasetoPublicMaker) ValidateToken(token string) (*Payload, error) {
+	var footer pasetoFooter
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, ok := maker.keyring.Lookup(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := maker.paseto.Verify(token, publicKey, payload, nil); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err := payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// JWKS returns the public keys other services can verify our tokens with
+func (maker *PasetoPublicMaker) JWKS() JWKS {
+	return maker.keyring.JWKS("")
+}
@@ -12,25 +12,57 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Token purposes restrict what a token may be used for
+const (
+	PurposeAccess      = "access"             // full session access
+	PurposeMFAPending  = "mfa_pending"        // password verified, waiting for a 2FA code
+	PurposeEmailVerify = "email_verification" // signed email verification link
+	PurposeAPIKey      = "api_key"            // request authenticated with a personal API key, never signed
+)
+
+// Claims describes the superuser and purpose a new token is issued for.
+type Claims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID // set for access tokens, identifies the login session in the session store
+	Username  string    // the email address the superuser logs in with
+	Role      string
+	Purpose   string
+	Duration  time.Duration
+}
+
+// Payload is the content of a validated token.
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
+	ID        uuid.UUID `json:"id"`         // token ID (jti), unique per token
+	UserID    uuid.UUID `json:"user_id"`    // subject, the superuser the token was issued to
+	SessionID uuid.UUID `json:"session_id"` // login session the token belongs to, nil for single-purpose tokens
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Purpose   string    `json:"purpose"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-// NewPayload creates a new token payload with the username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a fresh token ID for the given claims
+func NewPayload(claims Claims) (*Payload, error) {
+	if claims.UserID == uuid.Nil {
+		return nil, errors.New("token subject must be set")
+	}
+
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	payload := &Payload{
 		ID:        tokenID,
-		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		UserID:    claims.UserID,
+		SessionID: claims.SessionID,
+		Username:  claims.Username,
+		Role:      claims.Role,
+		Purpose:   claims.Purpose,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(claims.Duration),
 	}
 
 	return payload, nil
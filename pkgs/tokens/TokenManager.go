@@ -1,21 +1,33 @@
 package tokens
 
 import (
-	"time"
+	"fmt"
 
 	"github.com/lordofthemind/htmx_GO/internals/configs"
 )
 
 type TokenManager interface {
-	// CreateToken creates a new token for a specific user
-	GenerateToken(username string, duration time.Duration) (string, error)
+	// GenerateToken creates a new token for the given claims and returns it with its payload
+	GenerateToken(claims Claims) (string, *Payload, error)
 	// VerifyToken checks if the token is valid or not
 	ValidateToken(tokenString string) (*Payload, error)
 }
 
-func NewTokenManager() (TokenManager, error) {
-	if configs.UseJWT {
-		return NewJWTMaker()
+// NewTokenManager creates the token manager selected by the token configuration.
+// The keyring is only used, and then required, for asymmetric signing.
+func NewTokenManager(keyring *Keyring) (TokenManager, error) {
+	switch configs.TokenSigning {
+	case "", "symmetric":
+		if configs.UseJWT {
+			return NewJWTMaker()
+		}
+		return NewPasetoMaker()
+	case "asymmetric":
+		if configs.UseJWT {
+			return NewJWTEdDSAMaker(keyring)
+		}
+		return NewPasetoPublicMaker(keyring)
+	default:
+		return nil, fmt.Errorf("unknown token signing mode %q", configs.TokenSigning)
 	}
-	return NewPasetoMaker()
 }
@@ -0,0 +1,11 @@
+package tokens
+
+import "time"
+
+// TokenPair is the access token and refresh token handed to a client when a session starts or is refreshed.
+type TokenPair struct {
+	AccessToken      string
+	AccessPayload    *Payload
+	RefreshToken     string // empty when the refresh token was not rotated
+	RefreshExpiresAt time.Time
+}
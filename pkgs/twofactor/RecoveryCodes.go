@@ -0,0 +1,53 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// recoveryCodeAlphabet omits characters that are easily confused when written down (0/o, 1/l/i).
+const recoveryCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// recoveryCodeLength is the number of random characters in a code, split into two groups by a dash.
+const recoveryCodeLength = 10
+
+// GenerateRecoveryCodes returns count new random recovery codes formatted as "xxxxx-xxxxx".
+// Every character is drawn uniformly from the alphabet.
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := 0; i < count; i++ {
+		var code strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+			}
+			code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		codes = append(codes, code.String())
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode normalizes a recovery code and returns its hex encoded SHA-256 hash.
+// Codes carry enough entropy that a fast hash is sufficient and allows direct lookup.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeRecoveryCode lowercases a code and strips the separators users may type.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
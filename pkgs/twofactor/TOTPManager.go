@@ -0,0 +1,144 @@
+package twofactor
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"image/png"
+	"time"
+
+	"github.com/lordofthemind/htmx_GO/internals/configs"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+var (
+	ErrInvalidCode = errors.New("2FA code is invalid")
+	ErrReusedCode  = errors.New("2FA code has already been used")
+)
+
+// qrCodeSize is the width and height in pixels of the generated provisioning QR code.
+const qrCodeSize = 256
+
+// Enrollment holds everything a client needs to register a new TOTP secret
+// with an authenticator app.
+type Enrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte // PNG encoded otpauth:// URI
+}
+
+type TOTPManager interface {
+	// GenerateEnrollment creates a new secret, provisioning URI and QR code for an account
+	GenerateEnrollment(accountName string) (*Enrollment, error)
+	// EnrollmentFor renders the provisioning URI and QR code of an existing secret
+	EnrollmentFor(accountName, secret string) (*Enrollment, error)
+	// ValidateCode checks a code against the secret and returns the time step it matched.
+	// Steps at or before lastUsedStep are rejected to prevent replaying a code.
+	ValidateCode(secret, code string, lastUsedStep int64) (int64, error)
+}
+
+// TOTPMaker implements RFC 6238 time-based one-time passwords.
+type TOTPMaker struct {
+	issuer string
+	period uint
+	digits otp.Digits
+	skew   uint
+}
+
+// NewTOTPManager creates a new TOTPMaker from the totp configuration.
+func NewTOTPManager() (TOTPManager, error) {
+	if configs.TOTPIssuer == "" {
+		return nil, errors.New("totp issuer must be set in the configuration")
+	}
+
+	maker := &TOTPMaker{
+		issuer: configs.TOTPIssuer,
+		period: configs.TOTPPeriod,
+		digits: otp.Digits(configs.TOTPDigits),
+		skew:   configs.TOTPSkew,
+	}
+	if maker.period == 0 {
+		maker.period = 30
+	}
+	if maker.digits != otp.DigitsSix && maker.digits != otp.DigitsEight {
+		maker.digits = otp.DigitsSix
+	}
+
+	return maker, nil
+}
+
+// GenerateEnrollment creates a new random secret and renders its provisioning URI as a QR code.
+func (m *TOTPMaker) GenerateEnrollment(accountName string) (*Enrollment, error) {
+	return m.enrollment(accountName, nil)
+}
+
+// EnrollmentFor renders the provisioning URI and QR code of a secret generated earlier,
+// so a pending enrollment can be shown again without replacing it.
+func (m *TOTPMaker) EnrollmentFor(accountName, secret string) (*Enrollment, error) {
+	raw, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: %w", err)
+	}
+	return m.enrollment(accountName, raw)
+}
+
+// enrollment builds the enrollment for a raw secret, generating a random one when it is nil.
+func (m *TOTPMaker) enrollment(accountName string, secret []byte) (*Enrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      m.issuer,
+		AccountName: accountName,
+		Period:      m.period,
+		Digits:      m.digits,
+		Secret:      secret,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render totp qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode totp qr code: %w", err)
+	}
+
+	return &Enrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: buf.Bytes(),
+	}, nil
+}
+
+// ValidateCode checks the code against every time step inside the configured drift window.
+func (m *TOTPMaker) ValidateCode(secret, code string, lastUsedStep int64) (int64, error) {
+	opts := totp.ValidateOpts{
+		Period:    m.period,
+		Digits:    m.digits,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	current := time.Now().Unix() / int64(m.period)
+	for offset := -int64(m.skew); offset <= int64(m.skew); offset++ {
+		step := current + offset
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*int64(m.period), 0), opts)
+		if err != nil {
+			return 0, ErrInvalidCode
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			if step <= lastUsedStep {
+				return 0, ErrReusedCode
+			}
+			return step, nil
+		}
+	}
+
+	return 0, ErrInvalidCode
+}
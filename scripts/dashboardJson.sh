@@ -0,0 +1,14 @@
+#!/bin/bash
+
+# Logs in and calls a protected route with the access token as a Bearer header, no cookie jar needed
+TOKEN=$(curl -s -X POST http://localhost:9090/superuser/login \
+-H "Content-Type: application/json" \
+-H "Accept: application/json" \
+-d '{
+  "email": "testuser@example.com",
+  "password": "Sup3r-Secret-Pass"
+}' | sed -n 's/.*"access_token":"\([^"]*\)".*/\1/p')
+
+curl http://localhost:9090/superuser/dashboard \
+-H "Accept: application/json" \
+-H "Authorization: Bearer $TOKEN"
@@ -5,5 +5,5 @@ curl -X POST http://localhost:9090/superuser/login \
 -H "Accept": "text/html" \
 -d '{
   "email": "testuser@example.com",
-  "password": "password123"
+  "password": "Sup3r-Secret-Pass"
 }'
@@ -6,5 +6,5 @@ curl -X POST http://localhost:9090/superuser/register \
 -d '{
   "username": "testuser",
   "email": "testuser2@example.com",
-  "password": "password123"
+  "password": "Sup3r-Secret-Pass"
 }'